@@ -2,15 +2,11 @@ package main
 
 import (
 	"github.com/binhatch/go-turnstile/echoturnstile"
+	"github.com/binhatch/go-turnstile/turnstile"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
-const (
-	invalidSecret = "2x0000000000000000000000000000000AA"
-	validSecret   = "1x0000000000000000000000000000000AA"
-)
-
 func main() {
 	e := echo.New()
 
@@ -19,6 +15,6 @@ func main() {
 	})
 
 	e.Use(middleware.Logger())
-	e.Use(echoturnstile.NewMiddleware(invalidSecret))
+	e.Use(echoturnstile.NewMiddleware(turnstile.TestSecretAlwaysFails))
 	e.Start(":5432")
 }
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/binhatch/go-turnstile/turnstile"
+)
+
+func main() {
+	secret := flag.String("secret", os.Getenv("TURNSTILE_SECRET"), "turnstile secret key")
+	token := flag.String("token", "", "turnstile response token")
+	remoteIP := flag.String("remoteip", "", "client IP address")
+	flag.Parse()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	resp, err := turnstile.Verify(ctx, *secret, *token, *remoteIP)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("verified: hostname=%s action=%s challenge_ts=%s\n", resp.Hostname, resp.Action, resp.ChallengeTs)
+}
@@ -0,0 +1,287 @@
+package echoturnstile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultCloudFlareTurnstileJSONField = "cf-turnstile-response"
+	defaultCloudFlareTurnstileFormField = "cf-turnstile-response"
+	defaultGraphQLTurnstilePath         = "extensions.turnstile"
+	defaultMaxBodyBytes                 = 1 << 20
+
+	// DefaultMaxFormBytes is the default limit on form and multipart request
+	// bodies parsed to extract the token.
+	DefaultMaxFormBytes = 1 << 20
+)
+
+type jsonBodyTurnstileResponseExtractor struct {
+	path []string
+}
+
+func JSONBodyTurnstileResponseExtractorFunc() TurnstileResponseExtractorFunc {
+	return JSONBodyTurnstileResponseExtractorFuncWithFieldName(defaultCloudFlareTurnstileJSONField)
+}
+
+// JSONBodyTurnstileResponseExtractorFuncWithFieldName reads the token from the
+// given field of a JSON request body. Nested fields are addressed with a dotted
+// path such as "auth.turnstile". The body is restored for the next handler.
+func JSONBodyTurnstileResponseExtractorFuncWithFieldName(fieldName string) TurnstileResponseExtractorFunc {
+	return (&jsonBodyTurnstileResponseExtractor{path: strings.Split(fieldName, ".")}).Extract
+}
+
+func (e *jsonBodyTurnstileResponseExtractor) Extract(c echo.Context) (string, error) {
+	fieldName := strings.Join(e.path, ".")
+
+	body, err := readAndRestoreBody(c, defaultMaxBodyBytes)
+	if err != nil {
+		return "", err
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		return "", missingTokenError(fmt.Sprintf("expected turnstile response in JSON field %s", fieldName))
+	}
+
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return "", echo.NewHTTPError(echo.ErrBadRequest.Code, "malformed JSON request body").SetInternal(err)
+	}
+
+	for _, key := range e.path {
+		if doc == nil {
+			break
+		}
+
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return "", echo.NewHTTPError(echo.ErrBadRequest.Code,
+				fmt.Sprintf("expected JSON object along path %s", fieldName))
+		}
+
+		doc = obj[key]
+	}
+
+	val, _ := doc.(string)
+	if val == "" {
+		return "", missingTokenError(fmt.Sprintf("expected turnstile response in JSON field %s", fieldName))
+	}
+
+	return val, nil
+}
+
+// GraphQLTurnstileResponseExtractorFunc reads the token from the
+// extensions.turnstile field of a GraphQL POST body. The body is restored for
+// the GraphQL handler.
+func GraphQLTurnstileResponseExtractorFunc() TurnstileResponseExtractorFunc {
+	return GraphQLTurnstileResponseExtractorFuncWithPath(defaultGraphQLTurnstilePath)
+}
+
+// GraphQLTurnstileResponseExtractorFuncWithPath reads the token from the given
+// dotted path of a GraphQL POST body, e.g. "variables.captcha".
+func GraphQLTurnstileResponseExtractorFuncWithPath(path string) TurnstileResponseExtractorFunc {
+	return JSONBodyTurnstileResponseExtractorFuncWithFieldName(path)
+}
+
+type jsonPointerTurnstileResponseExtractor struct {
+	pointer string
+	tokens  []string
+}
+
+// JSONPointerTurnstileResponseExtractorFunc reads the token from the value an
+// RFC 6901 JSON Pointer such as "/data/auth/turnstile" refers to in a JSON
+// request body. Unlike dotted paths, pointers can address keys containing
+// dots and array elements. The body is restored for the next handler. It
+// panics if pointer is not a valid JSON Pointer.
+func JSONPointerTurnstileResponseExtractorFunc(pointer string) TurnstileResponseExtractorFunc {
+	if !strings.HasPrefix(pointer, "/") {
+		panic(fmt.Sprintf("echoturnstile: invalid JSON pointer %q", pointer))
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	return (&jsonPointerTurnstileResponseExtractor{pointer: pointer, tokens: tokens}).Extract
+}
+
+func (e *jsonPointerTurnstileResponseExtractor) Extract(c echo.Context) (string, error) {
+	body, err := readAndRestoreBody(c, defaultMaxBodyBytes)
+	if err != nil {
+		return "", err
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		return "", missingTokenError(fmt.Sprintf("expected turnstile response at JSON pointer %s", e.pointer))
+	}
+
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return "", echo.NewHTTPError(echo.ErrBadRequest.Code, "malformed JSON request body").SetInternal(err)
+	}
+
+	for _, token := range e.tokens {
+		switch node := doc.(type) {
+		case map[string]any:
+			doc = node[token]
+		case []any:
+			i, err := strconv.ParseUint(token, 10, 0)
+			if err != nil || i >= uint64(len(node)) || (len(token) > 1 && token[0] == '0') {
+				doc = nil
+			} else {
+				doc = node[i]
+			}
+		default:
+			doc = nil
+		}
+	}
+
+	val, _ := doc.(string)
+	if val == "" {
+		return "", missingTokenError(fmt.Sprintf("expected turnstile response at JSON pointer %s", e.pointer))
+	}
+
+	return val, nil
+}
+
+type trailerTurnstileResponseExtractor struct {
+	name string
+}
+
+// TrailerTurnstileResponseExtractorFunc reads the token from the named HTTP
+// trailer, for streaming clients sending it after the body. Trailers are only
+// available once the body was read to the end, so the extractor reads the
+// whole body, up to the same limit as the JSON extractors, and restores it for
+// the next handler.
+func TrailerTurnstileResponseExtractorFunc(name string) TurnstileResponseExtractorFunc {
+	return (&trailerTurnstileResponseExtractor{name: name}).Extract
+}
+
+func (e *trailerTurnstileResponseExtractor) Extract(c echo.Context) (string, error) {
+	if _, err := readAndRestoreBody(c, defaultMaxBodyBytes); err != nil {
+		return "", err
+	}
+
+	val := c.Request().Trailer.Get(e.name)
+	if val == "" {
+		return "", missingTokenError(fmt.Sprintf("expected turnstile response in trailer %s", e.name))
+	}
+
+	return val, nil
+}
+
+func readAndRestoreBody(c echo.Context, maxBytes int64) ([]byte, error) {
+	req := c.Request()
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("can not read request body: %w", err)
+	}
+
+	if int64(len(body)) > maxBytes {
+		return nil, echo.ErrStatusRequestEntityTooLarge
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// FormExtractorConfig configures the form and multipart extractors.
+type FormExtractorConfig struct {
+	// FieldName defaults to cf-turnstile-response, the field the widget adds
+	// to forms.
+	FieldName string
+	// MaxFormBytes limits the request body size; larger bodies are rejected
+	// with 413 Request Entity Too Large. Defaults to DefaultMaxFormBytes.
+	MaxFormBytes int64
+}
+
+func (cfg FormExtractorConfig) withDefaults() FormExtractorConfig {
+	if cfg.FieldName == "" {
+		cfg.FieldName = defaultCloudFlareTurnstileFormField
+	}
+
+	if cfg.MaxFormBytes <= 0 {
+		cfg.MaxFormBytes = DefaultMaxFormBytes
+	}
+
+	return cfg
+}
+
+type formTurnstileResponseExtractor struct {
+	cfg       FormExtractorConfig
+	multipart bool
+}
+
+func FormTurnstileResponseExtractorFunc() TurnstileResponseExtractorFunc {
+	return FormTurnstileResponseExtractorFuncWithConfig(FormExtractorConfig{})
+}
+
+// FormTurnstileResponseExtractorFuncWithConfig reads the token from an
+// application/x-www-form-urlencoded body. The parsed form stays available to
+// the next handler through c.FormValue.
+func FormTurnstileResponseExtractorFuncWithConfig(cfg FormExtractorConfig) TurnstileResponseExtractorFunc {
+	return (&formTurnstileResponseExtractor{cfg: cfg.withDefaults()}).Extract
+}
+
+func MultipartFormTurnstileResponseExtractorFunc() TurnstileResponseExtractorFunc {
+	return MultipartFormTurnstileResponseExtractorFuncWithConfig(FormExtractorConfig{})
+}
+
+// MultipartFormTurnstileResponseExtractorFuncWithConfig reads the token from a
+// multipart/form-data body. The parsed form stays available to the next
+// handler through c.FormValue and c.MultipartForm.
+func MultipartFormTurnstileResponseExtractorFuncWithConfig(cfg FormExtractorConfig) TurnstileResponseExtractorFunc {
+	return (&formTurnstileResponseExtractor{cfg: cfg.withDefaults(), multipart: true}).Extract
+}
+
+func (e *formTurnstileResponseExtractor) Extract(c echo.Context) (string, error) {
+	req := c.Request()
+	if req.Body != nil {
+		req.Body = http.MaxBytesReader(c.Response(), req.Body, e.cfg.MaxFormBytes)
+	}
+
+	var val string
+	if e.multipart {
+		if err := req.ParseMultipartForm(e.cfg.MaxFormBytes); err != nil {
+			return "", formParseError(err)
+		}
+
+		if values := req.MultipartForm.Value[e.cfg.FieldName]; len(values) > 0 {
+			val = values[0]
+		}
+	} else {
+		if err := req.ParseForm(); err != nil {
+			return "", formParseError(err)
+		}
+
+		val = req.PostForm.Get(e.cfg.FieldName)
+	}
+
+	if val == "" {
+		return "", missingTokenError(fmt.Sprintf("expected turnstile response in form field %s", e.cfg.FieldName))
+	}
+
+	return val, nil
+}
+
+func formParseError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return echo.ErrStatusRequestEntityTooLarge.WithInternal(err)
+	}
+
+	return echo.NewHTTPError(echo.ErrBadRequest.Code, "malformed form request body").SetInternal(err)
+}
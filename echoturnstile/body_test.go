@@ -0,0 +1,346 @@
+package echoturnstile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/binhatch/go-turnstile/turnstile"
+	"github.com/labstack/echo/v4"
+)
+
+func newBodyContext(contentType, body string) echo.Context {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, contentType)
+
+	return newContext(req)
+}
+
+func TestJSONBodyExtractorFieldName(t *testing.T) {
+	tests := []struct {
+		name      string
+		fieldName string
+		body      string
+		want      string
+	}{
+		{name: "default field", fieldName: defaultCloudFlareTurnstileJSONField, body: `{"cf-turnstile-response":"token"}`, want: "token"},
+		{name: "flat field", fieldName: "cfToken", body: `{"cfToken":"token"}`, want: "token"},
+		{name: "nested field", fieldName: "auth.turnstile", body: `{"auth":{"turnstile":"token"}}`, want: "token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newBodyContext(echo.MIMEApplicationJSON, tt.body)
+
+			got, err := JSONBodyTurnstileResponseExtractorFuncWithFieldName(tt.fieldName)(c)
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+
+			body, _ := io.ReadAll(c.Request().Body)
+			if string(body) != tt.body {
+				t.Errorf("restored body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestJSONBodyExtractorErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantMissing bool
+	}{
+		{name: "empty body", body: "", wantMissing: true},
+		{name: "whitespace body", body: " \n", wantMissing: true},
+		{name: "absent field", body: `{"other":"token"}`, wantMissing: true},
+		{name: "absent parent", body: `{"other":{}}`, wantMissing: true},
+		{name: "null parent", body: `{"auth":null}`, wantMissing: true},
+		{name: "non-string value", body: `{"auth":{"turnstile":42}}`, wantMissing: true},
+		{name: "parent not an object", body: `{"auth":"token"}`},
+		{name: "invalid JSON", body: `{"auth":`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newBodyContext(echo.MIMEApplicationJSON, tt.body)
+
+			_, err := JSONBodyTurnstileResponseExtractorFuncWithFieldName("auth.turnstile")(c)
+
+			var httpErr *echo.HTTPError
+			if !errors.As(err, &httpErr) || httpErr.Code != http.StatusBadRequest {
+				t.Fatalf("Extract() error = %v, want 400 HTTPError", err)
+			}
+			if got := errors.Is(err, ErrMissingTurnstileResponse); got != tt.wantMissing {
+				t.Errorf("errors.Is(err, ErrMissingTurnstileResponse) = %v, want %v", got, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func multipartBody(t *testing.T, fields map[string]string) (contentType, body string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for name, value := range fields {
+		if err := w.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return w.FormDataContentType(), buf.String()
+}
+
+func TestFormExtractorMaxFormBytes(t *testing.T) {
+	const maxFormBytes = 512
+
+	formFields := func(padding int) map[string]string {
+		return map[string]string{
+			defaultCloudFlareTurnstileFormField: "token",
+			"padding":                           strings.Repeat("x", padding),
+		}
+	}
+
+	tests := []struct {
+		name      string
+		multipart bool
+		padding   int
+		status    int
+	}{
+		{name: "form under limit", padding: 10},
+		{name: "form over limit", padding: 2 * maxFormBytes, status: http.StatusRequestEntityTooLarge},
+		{name: "multipart under limit", multipart: true, padding: 10},
+		{name: "multipart over limit", multipart: true, padding: 2 * maxFormBytes, status: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := FormExtractorConfig{MaxFormBytes: maxFormBytes}
+			extract := FormTurnstileResponseExtractorFuncWithConfig(cfg)
+
+			var c echo.Context
+			if tt.multipart {
+				extract = MultipartFormTurnstileResponseExtractorFuncWithConfig(cfg)
+				c = newBodyContext(multipartBody(t, formFields(tt.padding)))
+			} else {
+				values := url.Values{}
+				for name, value := range formFields(tt.padding) {
+					values.Set(name, value)
+				}
+				c = newBodyContext(echo.MIMEApplicationForm, values.Encode())
+			}
+
+			got, err := extract(c)
+			if tt.status != 0 {
+				var httpErr *echo.HTTPError
+				if !errors.As(err, &httpErr) || httpErr.Code != tt.status {
+					t.Errorf("Extract() error = %v, want %d HTTPError", err, tt.status)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if got != "token" {
+				t.Errorf("Extract() = %q, want %q", got, "token")
+			}
+			if v := c.FormValue("padding"); len(v) != tt.padding {
+				t.Errorf("FormValue(padding) has %d bytes, want %d", len(v), tt.padding)
+			}
+		})
+	}
+}
+
+func TestFormExtractorDefaults(t *testing.T) {
+	cfg := FormExtractorConfig{}.withDefaults()
+
+	if cfg.FieldName != defaultCloudFlareTurnstileFormField || cfg.MaxFormBytes != DefaultMaxFormBytes {
+		t.Errorf("withDefaults() = %+v, want field %q and limit %d",
+			cfg, defaultCloudFlareTurnstileFormField, DefaultMaxFormBytes)
+	}
+}
+
+func TestGraphQLExtractor(t *testing.T) {
+	const query = `"query":"mutation { signup }"`
+
+	tests := []struct {
+		name        string
+		extractor   TurnstileResponseExtractorFunc
+		body        string
+		want        string
+		wantMissing bool
+	}{
+		{name: "extensions token", extractor: GraphQLTurnstileResponseExtractorFunc(), body: `{` + query + `,"extensions":{"turnstile":"token"}}`, want: "token"},
+		{name: "missing extensions", extractor: GraphQLTurnstileResponseExtractorFunc(), body: `{` + query + `}`, wantMissing: true},
+		{name: "missing token", extractor: GraphQLTurnstileResponseExtractorFunc(), body: `{` + query + `,"extensions":{"persistedQuery":{}}}`, wantMissing: true},
+		{name: "custom path", extractor: GraphQLTurnstileResponseExtractorFuncWithPath("variables.captcha"), body: `{` + query + `,"variables":{"captcha":"token"}}`, want: "token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newBodyContext(echo.MIMEApplicationJSON, tt.body)
+
+			got, err := tt.extractor(c)
+			if got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+			if tt.wantMissing && !errors.Is(err, ErrMissingTurnstileResponse) {
+				t.Errorf("Extract() error = %v, want %v", err, ErrMissingTurnstileResponse)
+			}
+			if !tt.wantMissing && err != nil {
+				t.Errorf("Extract() error = %v", err)
+			}
+
+			body, _ := io.ReadAll(c.Request().Body)
+			if string(body) != tt.body {
+				t.Errorf("restored body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestJSONPointerExtractor(t *testing.T) {
+	tests := []struct {
+		name        string
+		pointer     string
+		body        string
+		want        string
+		wantMissing bool
+	}{
+		{name: "nested objects", pointer: "/data/auth/turnstile", body: `{"data":{"auth":{"turnstile":"token"}}}`, want: "token"},
+		{name: "array element", pointer: "/widgets/1/token", body: `{"widgets":[{"token":"first"},{"token":"token"}]}`, want: "token"},
+		{name: "escaped keys", pointer: "/a~1b/c~0d", body: `{"a/b":{"c~d":"token"}}`, want: "token"},
+		{name: "dotted key", pointer: "/cf.turnstile", body: `{"cf.turnstile":"token"}`, want: "token"},
+		{name: "empty body", pointer: "/data/turnstile", body: "", wantMissing: true},
+		{name: "absent key", pointer: "/data/turnstile", body: `{"data":{}}`, wantMissing: true},
+		{name: "index out of range", pointer: "/widgets/2", body: `{"widgets":["a","b"]}`, wantMissing: true},
+		{name: "leading zero index", pointer: "/widgets/01", body: `{"widgets":["a","b"]}`, wantMissing: true},
+		{name: "non-numeric index", pointer: "/widgets/first", body: `{"widgets":["a","b"]}`, wantMissing: true},
+		{name: "through a string", pointer: "/data/turnstile", body: `{"data":"token"}`, wantMissing: true},
+		{name: "non-string value", pointer: "/data", body: `{"data":42}`, wantMissing: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newBodyContext(echo.MIMEApplicationJSON, tt.body)
+
+			got, err := JSONPointerTurnstileResponseExtractorFunc(tt.pointer)(c)
+			if got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+			if tt.wantMissing && !errors.Is(err, ErrMissingTurnstileResponse) {
+				t.Errorf("Extract() error = %v, want %v", err, ErrMissingTurnstileResponse)
+			}
+			if !tt.wantMissing && err != nil {
+				t.Errorf("Extract() error = %v", err)
+			}
+
+			body, _ := io.ReadAll(c.Request().Body)
+			if string(body) != tt.body {
+				t.Errorf("restored body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestJSONPointerExtractorMalformedBody(t *testing.T) {
+	_, err := JSONPointerTurnstileResponseExtractorFunc("/data")(newBodyContext(echo.MIMEApplicationJSON, `{"data":`))
+
+	var httpErr *echo.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Code != http.StatusBadRequest || errors.Is(err, ErrMissingTurnstileResponse) {
+		t.Errorf("Extract() error = %v, want 400 HTTPError", err)
+	}
+}
+
+func TestJSONPointerExtractorInvalidPointerPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("JSONPointerTurnstileResponseExtractorFunc() with invalid pointer did not panic")
+		}
+	}()
+
+	JSONPointerTurnstileResponseExtractorFunc("data/turnstile")
+}
+
+func TestTrailerExtractor(t *testing.T) {
+	const trailer = "X-Turnstile-Token"
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "trailer token", token: "token", status: http.StatusOK},
+		{name: "missing trailer", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tokens, bodies []string
+			verifier := verifierFunc(func(ctx context.Context, req *turnstile.VerificationRequest) (*turnstile.VerificationResponse, error) {
+				tokens = append(tokens, req.Response)
+				return &turnstile.VerificationResponse{Success: true}, nil
+			})
+
+			e := echo.New()
+			e.POST("/", func(c echo.Context) error {
+				body, _ := io.ReadAll(c.Request().Body)
+				bodies = append(bodies, string(body))
+				return c.NoContent(http.StatusOK)
+			}, NewMiddlewareWithConfig("", Config{
+				TurnstileVerifier:              verifier,
+				TurnstileResponseExtractorFunc: TrailerTurnstileResponseExtractorFunc(trailer),
+			}))
+
+			srv := httptest.NewServer(e)
+			defer srv.Close()
+
+			req, err := http.NewRequest(http.MethodPost, srv.URL, io.NopCloser(strings.NewReader("streamed body")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Trailer = http.Header{trailer: nil}
+			if tt.token != "" {
+				req.Trailer.Set(trailer, tt.token)
+			}
+
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+
+			if tt.token == "" {
+				if len(tokens) != 0 {
+					t.Errorf("verified tokens = %v, want none", tokens)
+				}
+				return
+			}
+			if len(tokens) != 1 || tokens[0] != tt.token {
+				t.Errorf("verified tokens = %v, want [%s]", tokens, tt.token)
+			}
+			if len(bodies) != 1 || bodies[0] != "streamed body" {
+				t.Errorf("handler bodies = %q, want the restored body", bodies)
+			}
+		})
+	}
+}
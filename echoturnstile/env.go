@@ -0,0 +1,39 @@
+package echoturnstile
+
+import (
+	"errors"
+	"os"
+
+	"github.com/binhatch/go-turnstile/turnstile"
+	"github.com/labstack/echo/v4"
+)
+
+// Environment variables read by NewMiddlewareFromEnv.
+const (
+	EnvSecret = "TURNSTILE_SECRET"
+	EnvHeader = "TURNSTILE_HEADER"
+	EnvURL    = "TURNSTILE_URL"
+)
+
+var ErrMissingSecret = errors.New("missing turnstile secret in environment variable " + EnvSecret)
+
+// NewMiddlewareFromEnv builds the middleware from the environment: the secret
+// from TURNSTILE_SECRET, which is required, and optionally the token header
+// from TURNSTILE_HEADER and the siteverify URL from TURNSTILE_URL.
+func NewMiddlewareFromEnv() (echo.MiddlewareFunc, error) {
+	secret := os.Getenv(EnvSecret)
+	if secret == "" {
+		return nil, ErrMissingSecret
+	}
+
+	var cfg Config
+	if url := os.Getenv(EnvURL); url != "" {
+		cfg.TurnstileVerifier = turnstile.NewVerifierClientWithURL(secret, url)
+	}
+
+	if header := os.Getenv(EnvHeader); header != "" {
+		cfg.TurnstileResponseExtractorFunc = RequestHeaderTurnstileResponseExtractorFuncWithHeaderName(header)
+	}
+
+	return NewMiddlewareWithConfig(secret, cfg), nil
+}
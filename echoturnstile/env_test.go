@@ -0,0 +1,55 @@
+package echoturnstile
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewMiddlewareFromEnv(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Decode() error = %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	t.Setenv(EnvSecret, "env-secret")
+	t.Setenv(EnvURL, srv.URL)
+	t.Setenv(EnvHeader, "X-Captcha")
+
+	mw, err := NewMiddlewareFromEnv()
+	if err != nil {
+		t.Fatalf("NewMiddlewareFromEnv() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-Captcha", "token")
+	if rec := serve(t, mw, req); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if body["secret"] != "env-secret" {
+		t.Errorf("secret = %v, want %q", body["secret"], "env-secret")
+	}
+	if body["response"] != "token" {
+		t.Errorf("response = %v, want %q", body["response"], "token")
+	}
+}
+
+func TestNewMiddlewareFromEnvMissingSecret(t *testing.T) {
+	t.Setenv(EnvSecret, "")
+
+	mw, err := NewMiddlewareFromEnv()
+	if !errors.Is(err, ErrMissingSecret) {
+		t.Errorf("NewMiddlewareFromEnv() error = %v, want %v", err, ErrMissingSecret)
+	}
+	if mw != nil {
+		t.Error("NewMiddlewareFromEnv() returned a middleware, want nil")
+	}
+}
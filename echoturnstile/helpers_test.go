@@ -0,0 +1,70 @@
+package echoturnstile
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/binhatch/go-turnstile/turnstile"
+	"github.com/labstack/echo/v4"
+)
+
+type verifierFunc func(ctx context.Context, req *turnstile.VerificationRequest) (*turnstile.VerificationResponse, error)
+
+func (f verifierFunc) Verify(ctx context.Context, req *turnstile.VerificationRequest) (*turnstile.VerificationResponse, error) {
+	return f(ctx, req)
+}
+
+func passingVerifier() turnstile.Verifier {
+	return turnstile.NewOfflineVerifier(turnstile.TestSecretAlwaysPasses)
+}
+
+func failingVerifier() turnstile.Verifier {
+	return turnstile.NewOfflineVerifier(turnstile.TestSecretAlwaysFails)
+}
+
+// cdataVerifier returns a verifier accepting every token and reporting cdata.
+func cdataVerifier(cdata string) turnstile.Verifier {
+	return verifierFunc(func(ctx context.Context, req *turnstile.VerificationRequest) (*turnstile.VerificationResponse, error) {
+		return &turnstile.VerificationResponse{Success: true, Cdata: cdata}, nil
+	})
+}
+
+// unavailableVerifier returns a verifier whose siteverify endpoint answers
+// 503 Service Unavailable.
+func unavailableVerifier() turnstile.Verifier {
+	return turnstile.NewVerifierClientWithHandler("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+}
+
+func okHandler(c echo.Context) error {
+	return c.String(http.StatusOK, "ok")
+}
+
+func newTokenRequest(token string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	if token != "" {
+		req.Header.Set(DefaultTurnstileResponseHeader, token)
+	}
+
+	return req
+}
+
+func newContext(req *http.Request) echo.Context {
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+// serve runs req through mw in front of okHandler.
+func serve(t *testing.T, mw echo.MiddlewareFunc, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+
+	e := echo.New()
+	e.Any("/*", okHandler, mw)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	return rec
+}
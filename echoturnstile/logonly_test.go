@@ -0,0 +1,96 @@
+package echoturnstile
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/binhatch/go-turnstile/turnstile"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/goleak"
+)
+
+func TestLogOnlyMode(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	tests := []struct {
+		name     string
+		verifier turnstile.Verifier
+		token    string
+		wantErr  error
+	}{
+		{name: "verified", verifier: passingVerifier(), token: "token"},
+		{name: "failed", verifier: failingVerifier(), token: "token", wantErr: turnstile.ErrValidationFailed},
+		{name: "missing token", verifier: passingVerifier(), wantErr: ErrMissingTurnstileResponse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			var gotErr error
+			mw := NewMiddlewareWithConfig("", Config{
+				TurnstileVerifier: tt.verifier,
+				LogOnlyFunc: func(c echo.Context, resp *turnstile.VerificationResponse, err error) {
+					calls++
+					gotErr = err
+				},
+			})
+
+			if rec := serve(t, mw, newTokenRequest(tt.token)); rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+
+			if calls != 1 {
+				t.Fatalf("LogOnlyFunc called %d times, want 1", calls)
+			}
+
+			if tt.wantErr == nil && gotErr != nil || tt.wantErr != nil && !errors.Is(gotErr, tt.wantErr) {
+				t.Errorf("LogOnlyFunc error = %v, want %v", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLogOnlyModeRunsConcurrently(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	release := make(chan struct{})
+	verifier := verifierFunc(func(ctx context.Context, req *turnstile.VerificationRequest) (*turnstile.VerificationResponse, error) {
+		<-release
+		return &turnstile.VerificationResponse{Success: true}, nil
+	})
+
+	var resp *turnstile.VerificationResponse
+	mw := NewMiddlewareWithConfig("", Config{
+		TurnstileVerifier: verifier,
+		LogOnlyFunc: func(c echo.Context, r *turnstile.VerificationResponse, err error) {
+			resp = r
+		},
+	})
+
+	e := echo.New()
+	e.POST("/", func(c echo.Context) error {
+		// The handler runs while verification is still blocked.
+		close(release)
+		return c.NoContent(http.StatusNoContent)
+	}, mw)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.ServeHTTP(httptest.NewRecorder(), newTokenRequest("token"))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request did not complete, handler waited for verification")
+	}
+
+	if resp == nil || !resp.Success {
+		t.Errorf("LogOnlyFunc response = %+v, want success", resp)
+	}
+}
@@ -1,16 +1,50 @@
 package echoturnstile
 
 import (
+	"crypto/subtle"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/binhatch/go-turnstile/turnstile"
 	"github.com/labstack/echo/v4"
 	echomiddleware "github.com/labstack/echo/v4/middleware"
 )
 
 const (
-	defaultCloudFlareTurnstileHeaderKey = "cf-turnstile-response"
-	defaultCloudFlareRemoteIPHeader     = "CF-Connecting-IP"
+	// DefaultTurnstileResponseHeader is the header read by
+	// RequestHeaderTurnstileResponseExtractorFunc.
+	DefaultTurnstileResponseHeader = "cf-turnstile-response"
+	// DefaultRemoteIPHeader is the header read by
+	// CloudFlareRequestHeaderRemoteIPExtractor.
+	DefaultRemoteIPHeader = "CF-Connecting-IP"
+	// TrueClientIPHeader is the header some Cloudflare Enterprise setups use
+	// instead of DefaultRemoteIPHeader.
+	TrueClientIPHeader = "True-Client-IP"
+
+	// VerifyDurationContextKey is the echo context key under which the
+	// middleware stores the time.Duration spent verifying the token.
+	VerifyDurationContextKey = "turnstile.verify_duration"
+
+	defaultFailureResponse = "CloudFlare Turnstile verification failed"
+	verifiedContextKey     = "turnstile.verified"
+	verifiedByContextKey   = "turnstile.verified_by"
+	// loadSheddingRetryAfter is the Retry-After value, in seconds, of
+	// verifications shed under load.
+	loadSheddingRetryAfter = "1"
+)
+
+var (
+	// ErrMissingTurnstileResponse is the internal error of the HTTP errors
+	// returned by the token extractors when the request carries no token.
+	ErrMissingTurnstileResponse = errors.New("missing turnstile response")
+
+	ErrCdataMismatch = fmt.Errorf("turnstile cdata mismatch: %w", turnstile.ErrValidationFailed)
 )
 
 type middleware struct {
@@ -19,6 +53,24 @@ type middleware struct {
 	turnstileResponseExtractorFunc TurnstileResponseExtractorFunc
 	remoteIPExtractorFunc          RemoteIPExtractorFunc
 	idempotencyKeyExtractorFunc    IdempotencyKeyExtractorFunc
+	metricsHook                    MetricsHookFunc
+	failureResponse                any
+	logOnlyFunc                    LogOnlyFunc
+	verifiedHeader                 string
+	expectedCdataFunc              func(c echo.Context) string
+	allowMissingToken              bool
+	postVerify                     func(c echo.Context, resp *turnstile.VerificationResponse) error
+	multiToken                     bool
+	multiTokenExtractorFunc        MultiTokenExtractorFunc
+	multiTokenPolicy               MultiTokenPolicy
+	errorHandler                   func(c echo.Context, err error) error
+	idempotencyKeyHeader           string
+	monitor                        bool
+	validationFailureStatus        int
+	contextKey                     string
+	contextSetter                  func(c echo.Context, resp *turnstile.VerificationResponse)
+	accessLogger                   *slog.Logger
+	verifyOnlyIfTokenPresent       bool
 }
 
 type Config struct {
@@ -27,117 +79,660 @@ type Config struct {
 	TurnstileResponseExtractorFunc TurnstileResponseExtractorFunc
 	RemoteIPExtractorFunc          RemoteIPExtractorFunc
 	IdempotencyKeyExtractorFunc    IdempotencyKeyExtractorFunc
+	// MetricsHook, when set, is called once per request with its Outcome.
+	MetricsHook MetricsHookFunc
+	// FailureResponse is the message of the HTTP error returned when
+	// verification fails. Echo serializes it, so it may be a struct or map for
+	// JSON APIs. Defaults to a plain text message.
+	FailureResponse any
+	// LogOnlyFunc, when set, switches the middleware to logging-only mode:
+	// verification runs concurrently with the handler and never affects the
+	// response. The result is passed to LogOnlyFunc after the handler returns,
+	// so it can be attached to the access log.
+	LogOnlyFunc LogOnlyFunc
+	// SetVerifiedHeader, when non-empty, names a request header set to "true"
+	// for downstream handlers once verification passed. Any value sent by the
+	// client under that name is removed.
+	SetVerifiedHeader string
+	// ExpectedCdataFunc, when set, computes the cdata expected for a request,
+	// e.g. a server-issued nonce, and rejects tokens carrying different cdata.
+	// An empty expected value rejects the request.
+	ExpectedCdataFunc func(c echo.Context) string
+	// AllowMissingToken lets requests without a token through unverified,
+	// e.g. during a rollout grace period. Requests carrying a token are still
+	// verified and rejected if it is invalid.
+	AllowMissingToken bool
+	// PostVerify, when set, runs after a successful verification and may
+	// still reject the request, e.g. to rate-limit per verified hostname. A
+	// returned *echo.HTTPError is passed on as is, other errors result in a
+	// 500 Internal Server Error.
+	PostVerify func(c echo.Context, resp *turnstile.VerificationResponse) error
+	// MultiToken verifies every token returned by MultiTokenExtractorFunc
+	// instead of a single one, for pages with several widgets. Whether all or
+	// any of them must pass is set by MultiTokenPolicy. Failures are combined
+	// into one response. MultiToken is ignored in logging-only mode.
+	MultiToken              bool
+	MultiTokenExtractorFunc MultiTokenExtractorFunc
+	MultiTokenPolicy        MultiTokenPolicy
+	// ErrorHandler, when set, renders failures instead of returning them to
+	// echo. It receives the *echo.HTTPError the middleware would return and
+	// its result is returned in its place; see ProblemJSONErrorHandler.
+	ErrorHandler func(c echo.Context, err error) error
+	// IdempotencyKeyHeader, when non-empty, names a header set on both the
+	// request and the response to the idempotency key sent to Cloudflare, so
+	// downstream handlers and logs can correlate with it, e.g.
+	// "X-Idempotency-Key".
+	IdempotencyKeyHeader string
+	// Monitor verifies every request and reports its Outcome to MetricsHook,
+	// but always passes it on to the handler, for measuring the impact of
+	// enforcement before enabling it. Unlike LogOnlyFunc, verification
+	// completes before the handler runs; LogOnlyFunc takes precedence when
+	// both are set. Siteverify outages never block requests either, so no
+	// separate fail-open switch is needed. PostVerify is not run and requests
+	// are never marked as verified.
+	Monitor bool
+	// ValidationFailureStatus is the status code returned when verification
+	// fails, e.g. 403 or 422 for frontends expecting them. It must be a 4xx
+	// status. Defaults to 400 Bad Request.
+	ValidationFailureStatus int
+	// ContextKey, when non-empty, is the echo context key under which the
+	// *turnstile.VerificationResponse of a verified request is stored, so
+	// handlers can read its hostname or action.
+	ContextKey string
+	// ContextSetter, when set, is passed the response of every verified
+	// request, for teams storing it following their own context conventions.
+	ContextSetter func(c echo.Context, resp *turnstile.VerificationResponse)
+	// AccessLogger, when set, receives one structured line per processed
+	// request with its method, path, outcome, hostname and verification
+	// latency. Skipped requests are not logged, nor are tokens or secrets.
+	AccessLogger *slog.Logger
+	// VerifyOnlyIfTokenPresent skips requests carrying no token, for routes
+	// where only some submissions include a widget. Unlike AllowMissingToken,
+	// the request is passed on before any other verification step runs.
+	VerifyOnlyIfTokenPresent bool
 }
 
+type LogOnlyFunc func(c echo.Context, resp *turnstile.VerificationResponse, err error)
+
 func NewMiddleware(secret string) echo.MiddlewareFunc {
 	return NewMiddlewareWithConfig(secret, Config{})
 }
 
 func NewMiddlewareWithConfig(secret string, cfg Config) echo.MiddlewareFunc {
-	var skipper echomiddleware.Skipper
-	if cfg.Skipper == nil {
+	skipper := cfg.Skipper
+	if skipper == nil {
 		skipper = echomiddleware.DefaultSkipper
 	}
 
-	var turnstileVerifier turnstile.Verifier
-	if cfg.TurnstileVerifier == nil {
+	turnstileVerifier := cfg.TurnstileVerifier
+	if turnstileVerifier == nil {
 		turnstileVerifier = turnstile.NewVerifierClient(secret)
 	}
 
-	var turnstileResponseExtractorFunc TurnstileResponseExtractorFunc
-	if cfg.TurnstileResponseExtractorFunc == nil {
+	turnstileResponseExtractorFunc := cfg.TurnstileResponseExtractorFunc
+	if turnstileResponseExtractorFunc == nil {
 		turnstileResponseExtractorFunc = RequestHeaderTurnstileResponseExtractorFunc()
 	}
 
-	var remoteIpExtractorFunc RemoteIPExtractorFunc
-	if cfg.RemoteIPExtractorFunc == nil {
+	remoteIpExtractorFunc := cfg.RemoteIPExtractorFunc
+	if remoteIpExtractorFunc == nil {
 		remoteIpExtractorFunc = EchoRemoteIPExtractor
 	}
 
-	var idempotencyKeyExtractorFunc IdempotencyKeyExtractorFunc
-	if cfg.IdempotencyKeyExtractorFunc == nil {
+	idempotencyKeyExtractorFunc := cfg.IdempotencyKeyExtractorFunc
+	if idempotencyKeyExtractorFunc == nil {
 		idempotencyKeyExtractorFunc = EchoIdempotencyKeyExtractor
 	}
 
+	multiTokenExtractorFunc := cfg.MultiTokenExtractorFunc
+	if multiTokenExtractorFunc == nil {
+		multiTokenExtractorFunc = RequestHeaderMultiTokenExtractorFunc()
+	}
+
+	failureResponse := cfg.FailureResponse
+	if failureResponse == nil {
+		failureResponse = defaultFailureResponse
+	}
+
+	validationFailureStatus := cfg.ValidationFailureStatus
+	if validationFailureStatus == 0 {
+		validationFailureStatus = http.StatusBadRequest
+	}
+
+	if validationFailureStatus < 400 || validationFailureStatus > 499 {
+		panic(fmt.Sprintf("echoturnstile: ValidationFailureStatus must be a 4xx status, got %d", validationFailureStatus))
+	}
+
 	mw := &middleware{
 		skipper:                        skipper,
 		turnstileVerifier:              turnstileVerifier,
 		turnstileResponseExtractorFunc: turnstileResponseExtractorFunc,
 		remoteIPExtractorFunc:          remoteIpExtractorFunc,
 		idempotencyKeyExtractorFunc:    idempotencyKeyExtractorFunc,
+		metricsHook:                    cfg.MetricsHook,
+		failureResponse:                failureResponse,
+		logOnlyFunc:                    cfg.LogOnlyFunc,
+		verifiedHeader:                 cfg.SetVerifiedHeader,
+		expectedCdataFunc:              cfg.ExpectedCdataFunc,
+		allowMissingToken:              cfg.AllowMissingToken,
+		postVerify:                     cfg.PostVerify,
+		multiToken:                     cfg.MultiToken,
+		multiTokenExtractorFunc:        multiTokenExtractorFunc,
+		multiTokenPolicy:               cfg.MultiTokenPolicy,
+		errorHandler:                   cfg.ErrorHandler,
+		idempotencyKeyHeader:           cfg.IdempotencyKeyHeader,
+		monitor:                        cfg.Monitor,
+		validationFailureStatus:        validationFailureStatus,
+		contextKey:                     cfg.ContextKey,
+		contextSetter:                  cfg.ContextSetter,
+		accessLogger:                   cfg.AccessLogger,
+		verifyOnlyIfTokenPresent:       cfg.VerifyOnlyIfTokenPresent,
 	}
 
 	return mw.Process
 }
 
+// VerifyDurationFromContext returns how long the middleware spent verifying
+// the token of the current request, e.g. for access logs.
+func VerifyDurationFromContext(c echo.Context) (time.Duration, bool) {
+	d, ok := c.Get(VerifyDurationContextKey).(time.Duration)
+	return d, ok
+}
+
+// CookieCdataFunc returns an ExpectedCdataFunc implementing the double-submit
+// pattern: the cdata of the token must equal the value of the named cookie,
+// binding the token to the session that set it. Requests without the cookie
+// are rejected.
+func CookieCdataFunc(cookieName string) func(c echo.Context) string {
+	return func(c echo.Context) string {
+		cookie, err := c.Cookie(cookieName)
+		if err != nil {
+			return ""
+		}
+
+		return cookie.Value
+	}
+}
+
+// Option adjusts the Config used by Protect.
+type Option func(cfg *Config)
+
+// Protect returns a middleware verifying with the given verifier, meant to be
+// passed to individual routes or groups instead of e.Use:
+//
+//	e.POST("/signup", handler, echoturnstile.Protect(verifier))
+//
+// Extractors and skippers only see requests of the routes the middleware is
+// attached to, so it composes with other route middleware in the usual order.
+func Protect(verifier turnstile.Verifier, opts ...Option) echo.MiddlewareFunc {
+	cfg := Config{TurnstileVerifier: verifier}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return NewMiddlewareWithConfig("", cfg)
+}
+
 func (mw *middleware) Process(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		if mw.skipper(c) {
-			return next(c)
+		if mw.verifiedHeader != "" {
+			c.Request().Header.Del(mw.verifiedHeader)
 		}
 
-		turnstileResponseValue, err := mw.turnstileResponseExtractorFunc(c)
-		if err != nil {
-			return err
+		if mw.skipper(c) || (mw.verifyOnlyIfTokenPresent && !mw.tokenPresent(c)) {
+			mw.report(c, OutcomeSkipped, nil)
+			return next(c)
 		}
 
-		remoteIP, err := mw.remoteIPExtractorFunc(c)
-		if err != nil {
-			return err
+		if mw.logOnlyFunc != nil {
+			return mw.processLogOnly(c, next)
 		}
 
-		idempotencyKey, err := mw.idempotencyKeyExtractorFunc(c)
-		if err != nil {
-			return err
+		if mw.monitor {
+			resp, err := mw.verify(c)
+			mw.report(c, outcomeOf(err), resp)
+			return next(c)
 		}
 
-		req := &turnstile.VerificationRequest{
-			Response:       turnstileResponseValue,
-			RemoteIP:       remoteIP,
-			IdempotencyKey: idempotencyKey,
+		resp, err := mw.verify(c)
+		if err == nil && mw.postVerify != nil {
+			err = mw.postVerify(c, resp)
 		}
 
-		_, err = mw.turnstileVerifier.Verify(c.Request().Context(), req)
 		if err != nil {
-			if errors.Is(err, turnstile.ErrValidationFailed) {
-				return echo.NewHTTPError(echo.ErrBadRequest.Code, "CloudFlare Turnstile verification failed")
+			if mw.allowMissingToken && errors.Is(err, ErrMissingTurnstileResponse) {
+				mw.report(c, OutcomeSkipped, nil)
+				return next(c)
 			}
 
-			return err
+			mw.report(c, outcomeOf(err), resp)
+			return mw.fail(c, err)
+		}
+
+		c.Set(verifiedContextKey, resp)
+		c.Set(verifiedByContextKey, mw.turnstileVerifier)
+		if mw.contextKey != "" {
+			c.Set(mw.contextKey, resp)
+		}
+
+		if mw.contextSetter != nil {
+			mw.contextSetter(c, resp)
+		}
+
+		mw.report(c, OutcomeVerified, resp)
+		if mw.verifiedHeader != "" {
+			c.Request().Header.Set(mw.verifiedHeader, "true")
 		}
 
 		return next(c)
 	}
 }
 
+// httpError converts every failure into an *echo.HTTPError, keeping the
+// original error as Internal, so a custom echo HTTPErrorHandler can render all
+// of them uniformly:
+//
+//   - 400 Bad Request: missing token, including tokens Cloudflare reports as
+//     missing-input-response, or failed verification, the latter configurable
+//     through ValidationFailureStatus
+//   - 500 Internal Server Error: rejected request (e.g. wrong secret) or other
+//     internal failures
+//   - 502 Bad Gateway: siteverify unreachable or reporting an internal error
+//   - 503 Service Unavailable: verification shed by a fail-fast bounded
+//     verifier, see turnstile.ErrTooManyInFlight
+func (mw *middleware) httpError(err error) *echo.HTTPError {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+
+	var verr *turnstile.VerificationError
+	if errors.As(err, &verr) && slices.Contains(verr.ErrorCodes, turnstile.CodeMissingInputResponse) {
+		return echo.NewHTTPError(http.StatusBadRequest, "turnstile response missing or empty").SetInternal(err)
+	}
+
+	if errors.Is(err, turnstile.ErrTooManyInFlight) {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "too many CloudFlare Turnstile verifications in flight").SetInternal(err)
+	}
+
+	switch turnstile.KindOf(err) {
+	case turnstile.OutcomeKindValidationFailure:
+		return echo.NewHTTPError(mw.validationFailureStatus, mw.failureResponse).SetInternal(err)
+	case turnstile.OutcomeKindTransportError, turnstile.OutcomeKindServerError:
+		return echo.NewHTTPError(http.StatusBadGateway, "CloudFlare Turnstile unavailable").SetInternal(err)
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+}
+
+func (mw *middleware) fail(c echo.Context, err error) error {
+	httpErr := mw.httpError(err)
+	if errors.Is(err, turnstile.ErrTooManyInFlight) {
+		c.Response().Header().Set(echo.HeaderRetryAfter, loadSheddingRetryAfter)
+	}
+	if mw.errorHandler != nil {
+		return mw.errorHandler(c, httpErr)
+	}
+
+	return httpErr
+}
+
+func (mw *middleware) processLogOnly(c echo.Context, next echo.HandlerFunc) error {
+	type result struct {
+		resp     *turnstile.VerificationResponse
+		err      error
+		duration time.Duration
+	}
+
+	if resp, ok := mw.reusableResponse(c); ok {
+		handlerErr := next(c)
+		err := mw.checkReused(c, resp)
+		mw.report(c, outcomeOf(err), resp)
+		mw.logOnlyFunc(c, resp, err)
+		return handlerErr
+	}
+
+	// Extractors read from the echo context, which must not be shared with the
+	// handler's goroutine, so only the call to the verifier runs concurrently.
+	req, err := mw.buildRequest(c)
+	if err != nil {
+		handlerErr := next(c)
+		mw.report(c, OutcomeError, nil)
+		mw.logOnlyFunc(c, nil, err)
+		return handlerErr
+	}
+
+	ctx := c.Request().Context()
+	done := make(chan result, 1)
+	go func() {
+		start := time.Now()
+		resp, err := mw.turnstileVerifier.Verify(ctx, req)
+		done <- result{resp: resp, err: err, duration: time.Since(start)}
+	}()
+
+	handlerErr := next(c)
+
+	res := <-done
+	c.Set(VerifyDurationContextKey, res.duration)
+	if res.err == nil {
+		res.err = mw.checkResponse(c, res.resp)
+	}
+
+	mw.report(c, outcomeOf(res.err), res.resp)
+	mw.logOnlyFunc(c, res.resp, res.err)
+
+	return handlerErr
+}
+
+// reusableResponse returns the response a stacked instance already verified
+// for the request. Verifying the same token again would fail as
+// timeout-or-duplicate, so later instances reuse it if they can apply their
+// verifier's expectations to it, see checkReused.
+func (mw *middleware) reusableResponse(c echo.Context) (*turnstile.VerificationResponse, bool) {
+	resp, ok := c.Get(verifiedContextKey).(*turnstile.VerificationResponse)
+	if !ok {
+		return nil, false
+	}
+
+	if mw.verifiedBySelf(c) {
+		return resp, true
+	}
+
+	_, ok = mw.turnstileVerifier.(turnstile.ResponseChecker)
+	return resp, ok
+}
+
+// checkReused applies the checks of this instance to a response verified by a
+// stacked one. A response verified with another verifier has not been checked
+// against this verifier's expectations, e.g. WithExpectedAction of a route
+// protected by Protect, so they are applied as well.
+func (mw *middleware) checkReused(c echo.Context, resp *turnstile.VerificationResponse) error {
+	if !mw.verifiedBySelf(c) {
+		checker := mw.turnstileVerifier.(turnstile.ResponseChecker)
+		if err := checker.CheckResponse(c.Request().Context(), resp); err != nil {
+			return err
+		}
+	}
+
+	return mw.checkResponse(c, resp)
+}
+
+// verifiedBySelf reports whether the response stored for the request was
+// verified with this instance's verifier. Verifiers of incomparable types,
+// such as function types, are never considered the same.
+func (mw *middleware) verifiedBySelf(c echo.Context) bool {
+	verifier, _ := c.Get(verifiedByContextKey).(turnstile.Verifier)
+
+	typ := reflect.TypeOf(verifier)
+	return typ != nil && typ == reflect.TypeOf(mw.turnstileVerifier) && typ.Comparable() && verifier == mw.turnstileVerifier
+}
+
+func (mw *middleware) verify(c echo.Context) (*turnstile.VerificationResponse, error) {
+	if resp, ok := mw.reusableResponse(c); ok {
+		return resp, mw.checkReused(c, resp)
+	}
+
+	if mw.multiToken {
+		return mw.verifyMulti(c)
+	}
+
+	req, err := mw.buildRequest(c)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := mw.turnstileVerifier.Verify(c.Request().Context(), req)
+	c.Set(VerifyDurationContextKey, time.Since(start))
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, mw.checkResponse(c, resp)
+}
+
+// tokenPresent reports whether the request carries a token. Extractor
+// failures other than a missing token count as present, so verification
+// reports them.
+func (mw *middleware) tokenPresent(c echo.Context) bool {
+	if mw.multiToken {
+		tokens, err := mw.multiTokenExtractorFunc(c)
+		return len(tokens) > 0 || (err != nil && !errors.Is(err, ErrMissingTurnstileResponse))
+	}
+
+	token, err := mw.turnstileResponseExtractorFunc(c)
+	return token != "" || (err != nil && !errors.Is(err, ErrMissingTurnstileResponse))
+}
+
+func (mw *middleware) checkResponse(c echo.Context, resp *turnstile.VerificationResponse) error {
+	if mw.expectedCdataFunc != nil {
+		expected := mw.expectedCdataFunc(c)
+		if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(resp.Cdata)) != 1 {
+			return ErrCdataMismatch
+		}
+	}
+
+	return nil
+}
+
+func (mw *middleware) buildRequest(c echo.Context) (*turnstile.VerificationRequest, error) {
+	turnstileResponseValue, err := mw.turnstileResponseExtractorFunc(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if turnstileResponseValue == "" {
+		return nil, missingTokenError("expected turnstile response")
+	}
+
+	remoteIP, err := mw.remoteIPExtractorFunc(c)
+	if err != nil {
+		return nil, err
+	}
+
+	idempotencyKey, err := mw.idempotencyKeyExtractorFunc(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if mw.idempotencyKeyHeader != "" && idempotencyKey != "" {
+		c.Request().Header.Set(mw.idempotencyKeyHeader, idempotencyKey)
+		c.Response().Header().Set(mw.idempotencyKeyHeader, idempotencyKey)
+	}
+
+	return &turnstile.VerificationRequest{
+		Response:       turnstileResponseValue,
+		RemoteIP:       remoteIP,
+		IdempotencyKey: idempotencyKey,
+	}, nil
+}
+
+func (mw *middleware) report(c echo.Context, outcome Outcome, resp *turnstile.VerificationResponse) {
+	if mw.metricsHook != nil {
+		mw.metricsHook(c, outcome)
+	}
+
+	if mw.accessLogger != nil && outcome != OutcomeSkipped {
+		mw.logAccess(c, outcome, resp)
+	}
+}
+
+func (mw *middleware) logAccess(c echo.Context, outcome Outcome, resp *turnstile.VerificationResponse) {
+	req := c.Request()
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("path", req.URL.Path),
+		slog.String("outcome", outcome.String()),
+	}
+
+	if resp != nil {
+		attrs = append(attrs, slog.String("hostname", resp.Hostname))
+	}
+
+	if d, ok := VerifyDurationFromContext(c); ok {
+		attrs = append(attrs, slog.Duration("latency", d))
+	}
+
+	mw.accessLogger.LogAttrs(req.Context(), slog.LevelInfo, "turnstile verification", attrs...)
+}
+
+// TurnstileResponseExtractorFunc extracts the Turnstile token from a request.
+//
+// The middleware verifies before calling the next handler, so WebSocket
+// connections are gated on the initial HTTP GET, before the upgrade happens.
+// Use a header or query parameter extractor for upgrade requests; extractors
+// that read the request body are unsafe there, as upgrade requests carry no
+// body and reading it may interfere with the hijacked connection.
 type TurnstileResponseExtractorFunc func(c echo.Context) (string, error)
 
+func missingTokenError(msg string) error {
+	return echo.NewHTTPError(echo.ErrBadRequest.Code, msg).SetInternal(ErrMissingTurnstileResponse)
+}
+
 type requestHeaderTurnstileResponseExtractor struct {
 	headerName string
+	strict     bool
 }
 
 func RequestHeaderTurnstileResponseExtractorFunc() TurnstileResponseExtractorFunc {
-	return RequestHeaderTurnstileResponseExtractorFuncWithHeaderName(defaultCloudFlareTurnstileHeaderKey)
+	return RequestHeaderTurnstileResponseExtractorFuncWithHeaderName(DefaultTurnstileResponseHeader)
 }
 
 func RequestHeaderTurnstileResponseExtractorFuncWithHeaderName(headerName string) TurnstileResponseExtractorFunc {
 	return (&requestHeaderTurnstileResponseExtractor{headerName: headerName}).Extract
 }
 
+// StrictRequestHeaderTurnstileResponseExtractorFunc is like
+// RequestHeaderTurnstileResponseExtractorFunc, but rejects requests carrying
+// the header more than once with 400 Bad Request instead of using the first
+// value, surfacing tampering or misbehaving proxies.
+func StrictRequestHeaderTurnstileResponseExtractorFunc() TurnstileResponseExtractorFunc {
+	return StrictRequestHeaderTurnstileResponseExtractorFuncWithHeaderName(DefaultTurnstileResponseHeader)
+}
+
+func StrictRequestHeaderTurnstileResponseExtractorFuncWithHeaderName(headerName string) TurnstileResponseExtractorFunc {
+	return (&requestHeaderTurnstileResponseExtractor{headerName: headerName, strict: true}).Extract
+}
+
 func (e *requestHeaderTurnstileResponseExtractor) Extract(c echo.Context) (string, error) {
+	if e.strict && len(c.Request().Header.Values(e.headerName)) > 1 {
+		return "", echo.NewHTTPError(echo.ErrBadRequest.Code,
+			fmt.Sprintf("expected a single turnstile response in header %s", e.headerName))
+	}
+
 	val := c.Request().Header.Get(e.headerName)
 	if val == "" {
-		return "", echo.NewHTTPError(echo.ErrBadRequest.Code,
-			fmt.Sprintf("expected turnstile response in header %s", e.headerName))
+		return "", missingTokenError(fmt.Sprintf("expected turnstile response in header %s", e.headerName))
 	}
 
 	return val, nil
 }
 
+type headerListTurnstileResponseExtractor struct {
+	headerNames []string
+}
+
+// HeaderListTurnstileResponseExtractorFunc reads the token from the first of
+// the given headers that is present, in order.
+func HeaderListTurnstileResponseExtractorFunc(headerNames ...string) TurnstileResponseExtractorFunc {
+	return (&headerListTurnstileResponseExtractor{headerNames: headerNames}).Extract
+}
+
+func (e *headerListTurnstileResponseExtractor) Extract(c echo.Context) (string, error) {
+	for _, headerName := range e.headerNames {
+		if val := c.Request().Header.Get(headerName); val != "" {
+			return val, nil
+		}
+	}
+
+	return "", missingTokenError(fmt.Sprintf("expected turnstile response in one of the headers %s",
+		strings.Join(e.headerNames, ", ")))
+}
+
+type queryParamTurnstileResponseExtractor struct {
+	paramName string
+}
+
+func QueryParamTurnstileResponseExtractorFunc(paramName string) TurnstileResponseExtractorFunc {
+	return (&queryParamTurnstileResponseExtractor{paramName: paramName}).Extract
+}
+
+func (e *queryParamTurnstileResponseExtractor) Extract(c echo.Context) (string, error) {
+	val := c.QueryParam(e.paramName)
+	if val == "" {
+		return "", missingTokenError(fmt.Sprintf("expected turnstile response in query parameter %s", e.paramName))
+	}
+
+	return val, nil
+}
+
+type pathParamTurnstileResponseExtractor struct {
+	paramName string
+}
+
+// PathParamTurnstileResponseExtractorFunc reads the token from a route path
+// parameter, e.g. "token" for the route "/verify/:token".
+func PathParamTurnstileResponseExtractorFunc(paramName string) TurnstileResponseExtractorFunc {
+	return (&pathParamTurnstileResponseExtractor{paramName: paramName}).Extract
+}
+
+func (e *pathParamTurnstileResponseExtractor) Extract(c echo.Context) (string, error) {
+	val := c.Param(e.paramName)
+	if val == "" {
+		return "", missingTokenError(fmt.Sprintf("expected turnstile response in path parameter %s", e.paramName))
+	}
+
+	return val, nil
+}
+
+type authorizationSchemeTurnstileResponseExtractor struct {
+	scheme string
+}
+
+// AuthorizationSchemeTurnstileResponseExtractorFunc reads the token from an
+// Authorization header of the form "<scheme> <token>", e.g. "Turnstile abc".
+// The scheme is matched case-insensitively.
+func AuthorizationSchemeTurnstileResponseExtractorFunc(scheme string) TurnstileResponseExtractorFunc {
+	return (&authorizationSchemeTurnstileResponseExtractor{scheme: scheme}).Extract
+}
+
+func (e *authorizationSchemeTurnstileResponseExtractor) Extract(c echo.Context) (string, error) {
+	auth := c.Request().Header.Get(echo.HeaderAuthorization)
+	scheme, token, ok := strings.Cut(auth, " ")
+	if !ok || !strings.EqualFold(scheme, e.scheme) || strings.TrimSpace(token) == "" {
+		return "", missingTokenError(fmt.Sprintf("expected turnstile response in %s header with scheme %s",
+			echo.HeaderAuthorization, e.scheme))
+	}
+
+	return strings.TrimSpace(token), nil
+}
+
 type RemoteIPExtractorFunc func(c echo.Context) (string, error)
 
+// EchoRemoteIPExtractor uses echo's RealIP, falling back to the host part of
+// the connection's remote address. It returns an empty string, which is not
+// sent to Cloudflare, when neither yields a valid IP.
 func EchoRemoteIPExtractor(c echo.Context) (string, error) {
-	return c.RealIP(), nil
+	if ip := turnstile.NormalizeRemoteIP(c.RealIP()); ip != "" {
+		return ip, nil
+	}
+
+	return turnstile.NormalizeRemoteIP(c.Request().RemoteAddr), nil
+}
+
+// RemoteAddrRemoteIPExtractor returns an extractor for servers behind an L4
+// load balancer using the PROXY protocol. When proxyProtocol is set, the
+// listener already replaced the connection's remote address with the client
+// address, so it is used as is and X-Forwarded-For and similar headers are
+// ignored. Otherwise it behaves like EchoRemoteIPExtractor.
+func RemoteAddrRemoteIPExtractor(proxyProtocol bool) RemoteIPExtractorFunc {
+	if !proxyProtocol {
+		return EchoRemoteIPExtractor
+	}
+
+	return func(c echo.Context) (string, error) {
+		return turnstile.NormalizeRemoteIP(c.Request().RemoteAddr), nil
+	}
 }
 
 type requestHeaderRemoteIPExtractor struct {
@@ -147,14 +742,32 @@ type requestHeaderRemoteIPExtractor struct {
 func (e *requestHeaderRemoteIPExtractor) Extract(c echo.Context) (string, error) {
 	val := c.Request().Header.Get(e.headerName)
 	if val == "" {
-		return "", fmt.Errorf("expected turnstile response in header %s: %w", e.headerName, echo.ErrBadRequest)
+		return "", echo.NewHTTPError(echo.ErrBadRequest.Code,
+			fmt.Sprintf("expected remote IP in header %s", e.headerName))
 	}
 
-	return val, nil
+	return turnstile.NormalizeRemoteIP(val), nil
 }
 
 func CloudFlareRequestHeaderRemoteIPExtractor() RemoteIPExtractorFunc {
-	return (&requestHeaderRemoteIPExtractor{headerName: defaultCloudFlareRemoteIPHeader}).Extract
+	return (&requestHeaderRemoteIPExtractor{headerName: DefaultRemoteIPHeader}).Extract
+}
+
+func TrueClientIPRequestHeaderRemoteIPExtractor() RemoteIPExtractorFunc {
+	return (&requestHeaderRemoteIPExtractor{headerName: TrueClientIPHeader}).Extract
+}
+
+// CloudFlareRemoteIPExtractor tries the CF-Connecting-IP header, then
+// True-Client-IP, then falls back to EchoRemoteIPExtractor. Headers holding no
+// valid IP are skipped.
+func CloudFlareRemoteIPExtractor(c echo.Context) (string, error) {
+	for _, headerName := range []string{DefaultRemoteIPHeader, TrueClientIPHeader} {
+		if ip := turnstile.NormalizeRemoteIP(c.Request().Header.Get(headerName)); ip != "" {
+			return ip, nil
+		}
+	}
+
+	return EchoRemoteIPExtractor(c)
 }
 
 type IdempotencyKeyExtractorFunc func(c echo.Context) (string, error)
@@ -167,3 +780,48 @@ func EchoIdempotencyKeyExtractor(c echo.Context) (string, error) {
 
 	return requestId, nil
 }
+
+// RequestHeaderIdempotencyKeyExtractor returns an extractor reading the
+// idempotency key from the named header, e.g. "X-Idempotency-Key". It yields
+// an empty key when the header is absent.
+func RequestHeaderIdempotencyKeyExtractor(headerName string) IdempotencyKeyExtractorFunc {
+	return func(c echo.Context) (string, error) {
+		return c.Request().Header.Get(headerName), nil
+	}
+}
+
+// GeneratingIdempotencyKeyExtractor always generates a fresh idempotency key,
+// typically as the last extractor of ChainIdempotencyKeyExtractor.
+func GeneratingIdempotencyKeyExtractor(c echo.Context) (string, error) {
+	return echomiddleware.DefaultRequestIDConfig.Generator(), nil
+}
+
+// ChainIdempotencyKeyExtractor tries the given extractors in order and returns
+// the first non-empty key, e.g. X-Idempotency-Key, then X-Request-ID, then a
+// generated one:
+//
+//	ChainIdempotencyKeyExtractor(
+//		RequestHeaderIdempotencyKeyExtractor("X-Idempotency-Key"),
+//		RequestHeaderIdempotencyKeyExtractor(echo.HeaderXRequestID),
+//		GeneratingIdempotencyKeyExtractor,
+//	)
+//
+// An error from any extractor is returned immediately.
+func ChainIdempotencyKeyExtractor(extractors ...IdempotencyKeyExtractorFunc) IdempotencyKeyExtractorFunc {
+	return func(c echo.Context) (string, error) {
+		for _, extract := range extractors {
+			key, err := extract(c)
+			if err != nil || key != "" {
+				return key, err
+			}
+		}
+
+		return "", nil
+	}
+}
+
+// NoIdempotencyKeyExtractor never provides an idempotency key, so none is sent
+// to Cloudflare.
+func NoIdempotencyKeyExtractor(c echo.Context) (string, error) {
+	return "", nil
+}
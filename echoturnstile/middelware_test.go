@@ -0,0 +1,1377 @@
+package echoturnstile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/binhatch/go-turnstile/turnstile"
+	"github.com/labstack/echo/v4"
+)
+
+// recordingVerifier returns a verifier client whose siteverify stub accepts
+// every token and records the decoded request bodies.
+func recordingVerifier(t *testing.T, bodies *[]map[string]any) turnstile.Verifier {
+	return turnstile.NewVerifierClientWithHandler("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding siteverify request: %v", err)
+		}
+		*bodies = append(*bodies, body)
+
+		w.Write([]byte(`{"success":true,"hostname":"example.com"}`))
+	}))
+}
+
+func TestNoIdempotencyKeyExtractor(t *testing.T) {
+	var bodies []map[string]any
+	mw := NewMiddlewareWithConfig("", Config{
+		TurnstileVerifier:           recordingVerifier(t, &bodies),
+		IdempotencyKeyExtractorFunc: NoIdempotencyKeyExtractor,
+	})
+
+	if rec := serve(t, mw, newTokenRequest("token")); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if len(bodies) != 1 {
+		t.Fatalf("siteverify called %d times, want 1", len(bodies))
+	}
+
+	if key, ok := bodies[0]["idempotency_key"]; ok {
+		t.Errorf("idempotency_key = %v sent, want none", key)
+	}
+}
+
+func TestEchoIdempotencyKeyExtractorSendsKey(t *testing.T) {
+	var bodies []map[string]any
+	mw := NewMiddlewareWithConfig("", Config{TurnstileVerifier: recordingVerifier(t, &bodies)})
+
+	req := newTokenRequest("token")
+	req.Header.Set("X-Request-ID", "request-id")
+	serve(t, mw, req)
+
+	if len(bodies) != 1 || bodies[0]["idempotency_key"] != "request-id" {
+		t.Errorf("siteverify requests = %v, want one with idempotency_key request-id", bodies)
+	}
+}
+
+func TestWebSocketUpgradeVerifiedBeforeHandler(t *testing.T) {
+	var verified []string
+	verifier := verifierFunc(func(ctx context.Context, req *turnstile.VerificationRequest) (*turnstile.VerificationResponse, error) {
+		verified = append(verified, req.Response)
+		return &turnstile.VerificationResponse{Success: true}, nil
+	})
+
+	mw := NewMiddlewareWithConfig("", Config{
+		TurnstileVerifier:              verifier,
+		TurnstileResponseExtractorFunc: QueryParamTurnstileResponseExtractorFunc("token"),
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ws?token=ws-token", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+
+	if rec := serve(t, mw, req); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if len(verified) != 1 || verified[0] != "ws-token" {
+		t.Errorf("verified tokens = %q, want [ws-token]", verified)
+	}
+}
+
+func TestWebSocketUpgradeWithoutTokenRejected(t *testing.T) {
+	mw := NewMiddlewareWithConfig("", Config{
+		TurnstileVerifier:              passingVerifier(),
+		TurnstileResponseExtractorFunc: QueryParamTurnstileResponseExtractorFunc("token"),
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+
+	if rec := serve(t, mw, req); rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestFailureResponse(t *testing.T) {
+	mw := NewMiddlewareWithConfig("", Config{
+		TurnstileVerifier: failingVerifier(),
+		FailureResponse:   map[string]string{"error": "turnstile_failed"},
+	})
+
+	rec := serve(t, mw, newTokenRequest("token"))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %q is not JSON: %v", rec.Body, err)
+	}
+	if body["error"] != "turnstile_failed" {
+		t.Errorf("body = %v, want {error: turnstile_failed}", body)
+	}
+}
+
+func TestFailureResponseDefault(t *testing.T) {
+	rec := serve(t, NewMiddlewareWithConfig("", Config{TurnstileVerifier: failingVerifier()}), newTokenRequest("token"))
+
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %q is not JSON: %v", rec.Body, err)
+	}
+	if body["message"] != defaultFailureResponse {
+		t.Errorf("message = %q, want %q", body["message"], defaultFailureResponse)
+	}
+}
+
+func TestProtectSingleRoute(t *testing.T) {
+	e := echo.New()
+	e.POST("/signup", okHandler, Protect(failingVerifier(), func(cfg *Config) {
+		cfg.ValidationFailureStatus = http.StatusForbidden
+	}))
+	e.POST("/login", okHandler)
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{path: "/signup", status: http.StatusForbidden},
+		{path: "/login", status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := newTokenRequest("token")
+			req.URL.Path = tt.path
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestSetVerifiedHeader(t *testing.T) {
+	const header = "X-Turnstile-Verified"
+
+	tests := []struct {
+		name     string
+		verifier turnstile.Verifier
+		skip     bool
+		want     string
+		called   bool
+	}{
+		{name: "verified", verifier: passingVerifier(), want: "true", called: true},
+		{name: "failed", verifier: failingVerifier()},
+		{name: "skipped", verifier: passingVerifier(), skip: true, called: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := NewMiddlewareWithConfig("", Config{
+				TurnstileVerifier: tt.verifier,
+				Skipper:           func(echo.Context) bool { return tt.skip },
+				SetVerifiedHeader: header,
+			})
+
+			var called bool
+			var got string
+			e := echo.New()
+			e.Any("/*", func(c echo.Context) error {
+				called = true
+				got = c.Request().Header.Get(header)
+				return c.NoContent(http.StatusOK)
+			}, mw)
+
+			// Clients must not be able to set the header themselves.
+			req := newTokenRequest("token")
+			req.Header.Set(header, "spoofed")
+
+			e.ServeHTTP(httptest.NewRecorder(), req)
+
+			if called != tt.called {
+				t.Fatalf("handler called = %v, want %v", called, tt.called)
+			}
+			if got != tt.want {
+				t.Errorf("%s = %q, want %q", header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpectedCdataFunc(t *testing.T) {
+	tests := []struct {
+		name     string
+		cdata    string
+		expected string
+		status   int
+	}{
+		{name: "match", cdata: "nonce-1", expected: "nonce-1", status: http.StatusOK},
+		{name: "mismatch", cdata: "nonce-1", expected: "nonce-2", status: http.StatusBadRequest},
+		{name: "no expected value", cdata: "nonce-1", status: http.StatusBadRequest},
+		{name: "no cdata", expected: "nonce-1", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var outcomes []Outcome
+			mw := NewMiddlewareWithConfig("", Config{
+				TurnstileVerifier: cdataVerifier(tt.cdata),
+				ExpectedCdataFunc: func(echo.Context) string { return tt.expected },
+				MetricsHook: func(c echo.Context, outcome Outcome) {
+					outcomes = append(outcomes, outcome)
+				},
+			})
+
+			rec := serve(t, mw, newTokenRequest("token"))
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+
+			want := OutcomeVerified
+			if tt.status != http.StatusOK {
+				want = OutcomeFailed
+			}
+			if len(outcomes) != 1 || outcomes[0] != want {
+				t.Errorf("outcomes = %v, want [%v]", outcomes, want)
+			}
+		})
+	}
+}
+
+func TestAuthorizationSchemeExtractor(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    string
+		wantErr bool
+	}{
+		{name: "scheme", header: "Turnstile token", want: "token"},
+		{name: "scheme case-insensitive", header: "turnstile token", want: "token"},
+		{name: "extra whitespace", header: "Turnstile  token ", want: "token"},
+		{name: "wrong scheme", header: "Bearer token", wantErr: true},
+		{name: "scheme only", header: "Turnstile", wantErr: true},
+		{name: "empty token", header: "Turnstile ", wantErr: true},
+		{name: "missing header", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.header)
+			}
+
+			got, err := AuthorizationSchemeTurnstileResponseExtractorFunc("Turnstile")(newContext(req))
+			if tt.wantErr {
+				var httpErr *echo.HTTPError
+				if !errors.As(err, &httpErr) || httpErr.Code != http.StatusBadRequest {
+					t.Errorf("Extract() error = %v, want 400 HTTPError", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailuresAreHTTPErrors(t *testing.T) {
+	errExtract := errors.New("extract")
+
+	tests := []struct {
+		name   string
+		cfg    Config
+		token  string
+		status int
+	}{
+		{name: "missing token", cfg: Config{TurnstileVerifier: passingVerifier()}, status: http.StatusBadRequest},
+		{name: "failed verification", cfg: Config{TurnstileVerifier: failingVerifier()}, token: "token", status: http.StatusBadRequest},
+		{
+			name: "missing-input-response",
+			cfg: Config{TurnstileVerifier: verifierFunc(func(ctx context.Context, req *turnstile.VerificationRequest) (*turnstile.VerificationResponse, error) {
+				return passingVerifier().Verify(ctx, &turnstile.VerificationRequest{})
+			})},
+			token:  "token",
+			status: http.StatusBadRequest,
+		},
+		{name: "invalid request", cfg: Config{TurnstileVerifier: turnstile.NewOfflineVerifier("wrong")}, token: "token", status: http.StatusInternalServerError},
+		{name: "unavailable", cfg: Config{TurnstileVerifier: unavailableVerifier()}, token: "token", status: http.StatusBadGateway},
+		{
+			name: "token extractor error",
+			cfg: Config{
+				TurnstileVerifier:              passingVerifier(),
+				TurnstileResponseExtractorFunc: func(echo.Context) (string, error) { return "", errExtract },
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "remote IP extractor error",
+			cfg: Config{
+				TurnstileVerifier:     passingVerifier(),
+				RemoteIPExtractorFunc: func(echo.Context) (string, error) { return "", errExtract },
+			},
+			token:  "token",
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "post verify error",
+			cfg: Config{
+				TurnstileVerifier: passingVerifier(),
+				PostVerify: func(echo.Context, *turnstile.VerificationResponse) error {
+					return errExtract
+				},
+			},
+			token:  "token",
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "cdata mismatch",
+			cfg: Config{
+				TurnstileVerifier: cdataVerifier("a"),
+				ExpectedCdataFunc: func(echo.Context) string { return "b" },
+			},
+			token:  "token",
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := NewMiddlewareWithConfig("", tt.cfg)
+
+			err := mw(okHandler)(newContext(newTokenRequest(tt.token)))
+
+			httpErr, ok := err.(*echo.HTTPError)
+			if !ok {
+				t.Fatalf("error = %#v, want *echo.HTTPError", err)
+			}
+			if httpErr.Code != tt.status {
+				t.Errorf("status = %d, want %d", httpErr.Code, tt.status)
+			}
+			if httpErr.Internal == nil {
+				t.Error("Internal = nil, want the original error")
+			}
+		})
+	}
+}
+
+func TestTokenTooLongIsBadRequest(t *testing.T) {
+	verifier := turnstile.NewVerifierClientWithHandler("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("siteverify called for an over-long token")
+	}), turnstile.WithMaxTokenLength(16))
+
+	rec := serve(t, Protect(verifier), newTokenRequest(strings.Repeat("x", 17)))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestDefaultHeaderConstants(t *testing.T) {
+	if DefaultTurnstileResponseHeader != "cf-turnstile-response" || DefaultRemoteIPHeader != "CF-Connecting-IP" {
+		t.Errorf("default headers = %q, %q", DefaultTurnstileResponseHeader, DefaultRemoteIPHeader)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set(DefaultTurnstileResponseHeader, "token")
+	req.Header.Set(DefaultRemoteIPHeader, "203.0.113.1")
+	c := newContext(req)
+
+	if got, err := RequestHeaderTurnstileResponseExtractorFunc()(c); err != nil || got != "token" {
+		t.Errorf("RequestHeaderTurnstileResponseExtractorFunc() = %q, %v, want %q", got, err, "token")
+	}
+	if got, err := CloudFlareRequestHeaderRemoteIPExtractor()(c); err != nil || got != "203.0.113.1" {
+		t.Errorf("CloudFlareRequestHeaderRemoteIPExtractor() = %q, %v, want %q", got, err, "203.0.113.1")
+	}
+}
+
+func TestVerifyDurationFromContext(t *testing.T) {
+	const delay = time.Millisecond
+
+	tests := []struct {
+		name   string
+		skip   bool
+		wantOK bool
+	}{
+		{name: "verified", wantOK: true},
+		{name: "skipped", skip: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := NewMiddlewareWithConfig("", Config{
+				TurnstileVerifier: verifierFunc(func(ctx context.Context, req *turnstile.VerificationRequest) (*turnstile.VerificationResponse, error) {
+					time.Sleep(delay)
+					return passingVerifier().Verify(ctx, req)
+				}),
+				Skipper: func(echo.Context) bool { return tt.skip },
+			})
+
+			var got time.Duration
+			var ok bool
+			err := mw(func(c echo.Context) error {
+				got, ok = VerifyDurationFromContext(c)
+				return nil
+			})(newContext(newTokenRequest("token")))
+			if err != nil {
+				t.Fatalf("middleware error = %v", err)
+			}
+
+			if ok != tt.wantOK {
+				t.Fatalf("VerifyDurationFromContext() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if tt.wantOK && got < delay {
+				t.Errorf("VerifyDurationFromContext() = %v, want at least %v", got, delay)
+			}
+		})
+	}
+}
+
+func TestHeaderListExtractor(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{name: "first hit", headers: map[string]string{"X-Token-A": "a", "X-Token-B": "b"}, want: "a"},
+		{name: "later hit", headers: map[string]string{"X-Token-B": "b"}, want: "b"},
+		{name: "empty header skipped", headers: map[string]string{"X-Token-A": "", "X-Token-B": "b"}, want: "b"},
+		{name: "none present"},
+	}
+
+	extract := HeaderListTurnstileResponseExtractorFunc("X-Token-A", "X-Token-B")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+
+			got, err := extract(newContext(req))
+			if tt.want == "" {
+				if !errors.Is(err, ErrMissingTurnstileResponse) {
+					t.Errorf("Extract() error = %v, want ErrMissingTurnstileResponse", err)
+				}
+				return
+			}
+
+			if err != nil || got != tt.want {
+				t.Errorf("Extract() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEchoRemoteIPExtractor(t *testing.T) {
+	tests := []struct {
+		name       string
+		realIP     string
+		remoteAddr string
+		want       string
+	}{
+		{name: "real IP", realIP: "203.0.113.1", remoteAddr: "192.0.2.1:1234", want: "203.0.113.1"},
+		{name: "invalid real IP", realIP: "unknown", remoteAddr: "192.0.2.1:1234", want: "192.0.2.1"},
+		{name: "remote address", remoteAddr: "[2001:db8::1]:1234", want: "2001:db8::1"},
+		{name: "unknown", remoteAddr: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.realIP != "" {
+				req.Header.Set(echo.HeaderXRealIP, tt.realIP)
+			}
+
+			got, err := EchoRemoteIPExtractor(newContext(req))
+			if err != nil || got != tt.want {
+				t.Errorf("EchoRemoteIPExtractor() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmptyRemoteIPNotSent(t *testing.T) {
+	var bodies []map[string]any
+	mw := NewMiddlewareWithConfig("", Config{TurnstileVerifier: recordingVerifier(t, &bodies)})
+
+	req := newTokenRequest("token")
+	req.RemoteAddr = ""
+	serve(t, mw, req)
+
+	if len(bodies) != 1 {
+		t.Fatalf("siteverify called %d times, want 1", len(bodies))
+	}
+	if ip, ok := bodies[0]["remoteip"]; ok {
+		t.Errorf("remoteip = %v sent, want none", ip)
+	}
+}
+
+func TestPathParamExtractor(t *testing.T) {
+	var tokens []string
+	mw := NewMiddlewareWithConfig("", Config{
+		TurnstileVerifier: verifierFunc(func(ctx context.Context, req *turnstile.VerificationRequest) (*turnstile.VerificationResponse, error) {
+			tokens = append(tokens, req.Response)
+			return passingVerifier().Verify(ctx, req)
+		}),
+		TurnstileResponseExtractorFunc: PathParamTurnstileResponseExtractorFunc("token"),
+	})
+
+	e := echo.New()
+	e.GET("/verify/:token", okHandler, mw)
+	e.GET("/verify/", okHandler, mw)
+
+	tests := []struct {
+		path   string
+		status int
+		token  string
+	}{
+		{path: "/verify/abc", status: http.StatusOK, token: "abc"},
+		{path: "/verify/", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			tokens = nil
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.token != "" && (len(tokens) != 1 || tokens[0] != tt.token) {
+				t.Errorf("verified tokens = %q, want [%q]", tokens, tt.token)
+			}
+			if tt.token == "" && len(tokens) != 0 {
+				t.Errorf("verified tokens = %q, want none", tokens)
+			}
+		})
+	}
+}
+
+func TestAllowMissingToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier turnstile.Verifier
+		token    string
+		status   int
+		want     Outcome
+	}{
+		{name: "missing token", verifier: failingVerifier(), status: http.StatusOK, want: OutcomeSkipped},
+		{name: "valid token", verifier: passingVerifier(), token: "token", status: http.StatusOK, want: OutcomeVerified},
+		{name: "invalid token", verifier: failingVerifier(), token: "token", status: http.StatusBadRequest, want: OutcomeFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var outcomes []Outcome
+			mw := NewMiddlewareWithConfig("", Config{
+				TurnstileVerifier: tt.verifier,
+				AllowMissingToken: true,
+				MetricsHook: func(c echo.Context, outcome Outcome) {
+					outcomes = append(outcomes, outcome)
+				},
+			})
+
+			rec := serve(t, mw, newTokenRequest(tt.token))
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if len(outcomes) != 1 || outcomes[0] != tt.want {
+				t.Errorf("outcomes = %v, want [%v]", outcomes, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostVerify(t *testing.T) {
+	errLimited := echo.NewHTTPError(http.StatusTooManyRequests, "rate limited")
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		want   Outcome
+	}{
+		{name: "allows", status: http.StatusOK, want: OutcomeVerified},
+		{name: "rejects with HTTPError", err: errLimited, status: http.StatusTooManyRequests, want: OutcomeError},
+		{name: "rejects with error", err: errors.New("boom"), status: http.StatusInternalServerError, want: OutcomeError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hostnames []string
+			var outcomes []Outcome
+			mw := NewMiddlewareWithConfig("", Config{
+				TurnstileVerifier: passingVerifier(),
+				PostVerify: func(c echo.Context, resp *turnstile.VerificationResponse) error {
+					hostnames = append(hostnames, resp.Hostname)
+					return tt.err
+				},
+				MetricsHook: func(c echo.Context, outcome Outcome) {
+					outcomes = append(outcomes, outcome)
+				},
+			})
+
+			rec := serve(t, mw, newTokenRequest("token"))
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if len(hostnames) != 1 || hostnames[0] == "" {
+				t.Errorf("PostVerify called with hostnames %q, want one verified hostname", hostnames)
+			}
+			if len(outcomes) != 1 || outcomes[0] != tt.want {
+				t.Errorf("outcomes = %v, want [%v]", outcomes, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostVerifyNotCalledOnFailure(t *testing.T) {
+	mw := NewMiddlewareWithConfig("", Config{
+		TurnstileVerifier: failingVerifier(),
+		PostVerify: func(echo.Context, *turnstile.VerificationResponse) error {
+			t.Error("PostVerify called for a failed verification")
+			return nil
+		},
+	})
+
+	if rec := serve(t, mw, newTokenRequest("token")); rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCookieCdataFunc(t *testing.T) {
+	const cookieName = "session_nonce"
+
+	tests := []struct {
+		name   string
+		cookie string
+		cdata  string
+		status int
+	}{
+		{name: "match", cookie: "nonce-1", cdata: "nonce-1", status: http.StatusOK},
+		{name: "mismatch", cookie: "nonce-1", cdata: "nonce-2", status: http.StatusBadRequest},
+		{name: "missing cookie", cdata: "nonce-1", status: http.StatusBadRequest},
+		{name: "missing cookie and cdata", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := NewMiddlewareWithConfig("", Config{
+				TurnstileVerifier: cdataVerifier(tt.cdata),
+				ExpectedCdataFunc: CookieCdataFunc(cookieName),
+			})
+
+			req := newTokenRequest("token")
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: cookieName, Value: tt.cookie})
+			}
+
+			if rec := serve(t, mw, req); rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+type countedVerifier struct {
+	v     turnstile.Verifier
+	calls *int
+}
+
+func (v *countedVerifier) Verify(ctx context.Context, req *turnstile.VerificationRequest) (*turnstile.VerificationResponse, error) {
+	*v.calls++
+	return v.v.Verify(ctx, req)
+}
+
+// countingVerifier wraps v, counting its calls. Unlike a verifierFunc, the
+// result is comparable, so stacked instances recognize it as the same
+// verifier.
+func countingVerifier(v turnstile.Verifier, calls *int) turnstile.Verifier {
+	return &countedVerifier{v: v, calls: calls}
+}
+
+func TestStackedMiddlewareVerifiesOnce(t *testing.T) {
+	var calls int
+	verifier := countingVerifier(passingVerifier(), &calls)
+
+	var outcomes []Outcome
+	outer := Protect(verifier)
+	inner := Protect(verifier, func(cfg *Config) {
+		cfg.ContextKey = "turnstile"
+		cfg.MetricsHook = func(c echo.Context, outcome Outcome) {
+			outcomes = append(outcomes, outcome)
+		}
+	})
+
+	var stored any
+	e := echo.New()
+	e.POST("/", func(c echo.Context) error {
+		stored = c.Get("turnstile")
+		return c.NoContent(http.StatusOK)
+	}, outer, inner)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, newTokenRequest("token"))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if calls != 1 {
+		t.Errorf("verifier called %d times, want 1", calls)
+	}
+	if _, ok := stored.(*turnstile.VerificationResponse); !ok {
+		t.Errorf("stacked ContextKey value = %#v, want the stored response", stored)
+	}
+	if len(outcomes) != 1 || outcomes[0] != OutcomeVerified {
+		t.Errorf("stacked outcomes = %v, want [%v]", outcomes, OutcomeVerified)
+	}
+}
+
+func TestStackedMiddlewareAppliesStricterChecks(t *testing.T) {
+	var calls int
+	verifier := countingVerifier(cdataVerifier("nonce-1"), &calls)
+
+	tests := []struct {
+		name     string
+		expected string
+		status   int
+	}{
+		{name: "passes", expected: "nonce-1", status: http.StatusOK},
+		{name: "cdata mismatch", expected: "nonce-2", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = 0
+
+			var postVerified bool
+			inner := Protect(verifier, func(cfg *Config) {
+				cfg.ExpectedCdataFunc = func(echo.Context) string { return tt.expected }
+				cfg.PostVerify = func(echo.Context, *turnstile.VerificationResponse) error {
+					postVerified = true
+					return nil
+				}
+			})
+
+			e := echo.New()
+			e.POST("/", okHandler, Protect(verifier), inner)
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, newTokenRequest("token"))
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if calls != 1 {
+				t.Errorf("verifier called %d times, want 1", calls)
+			}
+			if postVerified != (tt.status == http.StatusOK) {
+				t.Errorf("stacked PostVerify called = %v, want %v", postVerified, tt.status == http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestStackedMiddlewareAppliesRouteVerifierExpectations(t *testing.T) {
+	siteverify := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"hostname":"example.com","action":"comment"}`))
+	})
+
+	var globalCalls, routeCalls int
+	global := countingVerifier(turnstile.NewVerifierClientWithHandler("secret", siteverify), &globalCalls)
+	signup := turnstile.NewVerifierClientWithHandler("secret", siteverify, turnstile.WithExpectedAction("signup"))
+
+	tests := []struct {
+		name      string
+		global    bool
+		route     turnstile.Verifier
+		status    int
+		wantCalls int
+	}{
+		{name: "route only", route: signup, status: http.StatusBadRequest},
+		{name: "stacked", global: true, route: signup, status: http.StatusBadRequest},
+		{name: "stacked without expectations", global: true, route: turnstile.NewVerifierClientWithHandler("secret", siteverify), status: http.StatusOK},
+		{name: "stacked with opaque verifier", global: true, route: countingVerifier(passingVerifier(), &routeCalls), status: http.StatusOK, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routeCalls = 0
+
+			e := echo.New()
+			if tt.global {
+				e.Use(NewMiddlewareWithConfig("", Config{TurnstileVerifier: global}))
+			}
+			e.POST("/signup", okHandler, Protect(tt.route))
+
+			req := newTokenRequest("token")
+			req.URL.Path = "/signup"
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if routeCalls != tt.wantCalls {
+				t.Errorf("route verifier called %d times, want %d", routeCalls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRemoteAddrRemoteIPExtractor(t *testing.T) {
+	tests := []struct {
+		name          string
+		proxyProtocol bool
+		remoteAddr    string
+		xff           string
+		want          string
+	}{
+		{name: "PROXY protocol", proxyProtocol: true, remoteAddr: "203.0.113.1:1234", want: "203.0.113.1"},
+		{name: "PROXY protocol ignores XFF", proxyProtocol: true, remoteAddr: "203.0.113.1:1234", xff: "198.51.100.1", want: "203.0.113.1"},
+		{name: "PROXY protocol IPv6", proxyProtocol: true, remoteAddr: "[2001:db8::1]:1234", want: "2001:db8::1"},
+		{name: "PROXY protocol unknown", proxyProtocol: true, remoteAddr: "", want: ""},
+		{name: "without PROXY protocol", remoteAddr: "203.0.113.1:1234", xff: "198.51.100.1", want: "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				req.Header.Set(echo.HeaderXForwardedFor, tt.xff)
+			}
+
+			got, err := RemoteAddrRemoteIPExtractor(tt.proxyProtocol)(newContext(req))
+			if err != nil || got != tt.want {
+				t.Errorf("RemoteAddrRemoteIPExtractor(%v)() = %q, %v, want %q", tt.proxyProtocol, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeyHeader(t *testing.T) {
+	const header = "X-Idempotency-Key"
+
+	var bodies []map[string]any
+	mw := NewMiddlewareWithConfig("", Config{
+		TurnstileVerifier:    recordingVerifier(t, &bodies),
+		IdempotencyKeyHeader: header,
+	})
+
+	var downstream string
+	e := echo.New()
+	e.POST("/", func(c echo.Context) error {
+		downstream = c.Request().Header.Get(header)
+		return c.NoContent(http.StatusOK)
+	}, mw)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, newTokenRequest("token"))
+
+	if len(bodies) != 1 {
+		t.Fatalf("siteverify called %d times, want 1", len(bodies))
+	}
+
+	sent, _ := bodies[0]["idempotency_key"].(string)
+	if sent == "" {
+		t.Fatal("no idempotency_key sent to siteverify")
+	}
+	if downstream != sent {
+		t.Errorf("request header %s = %q, want %q", header, downstream, sent)
+	}
+	if got := rec.Header().Get(header); got != sent {
+		t.Errorf("response header %s = %q, want %q", header, got, sent)
+	}
+}
+
+func TestRequestHeaderExtractorDuplicates(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     []string
+		want       string
+		strictFail bool
+	}{
+		{name: "single", values: []string{"a"}, want: "a"},
+		{name: "duplicate identical", values: []string{"a", "a"}, want: "a", strictFail: true},
+		{name: "duplicate differing", values: []string{"a", "b"}, want: "a", strictFail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			for _, value := range tt.values {
+				req.Header.Add(DefaultTurnstileResponseHeader, value)
+			}
+			c := newContext(req)
+
+			if got, err := RequestHeaderTurnstileResponseExtractorFunc()(c); err != nil || got != tt.want {
+				t.Errorf("lenient Extract() = %q, %v, want %q", got, err, tt.want)
+			}
+
+			got, err := StrictRequestHeaderTurnstileResponseExtractorFunc()(c)
+			if !tt.strictFail {
+				if err != nil || got != tt.want {
+					t.Errorf("strict Extract() = %q, %v, want %q", got, err, tt.want)
+				}
+				return
+			}
+
+			var httpErr *echo.HTTPError
+			if !errors.As(err, &httpErr) || httpErr.Code != http.StatusBadRequest {
+				t.Errorf("strict Extract() error = %v, want 400 HTTPError", err)
+			}
+		})
+	}
+}
+
+func TestValidationFailureStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured int
+		verifier   turnstile.Verifier
+		token      string
+		want       int
+	}{
+		{name: "default", verifier: failingVerifier(), token: "token", want: http.StatusBadRequest},
+		{name: "forbidden", configured: http.StatusForbidden, verifier: failingVerifier(), token: "token", want: http.StatusForbidden},
+		{name: "unprocessable", configured: http.StatusUnprocessableEntity, verifier: failingVerifier(), token: "token", want: http.StatusUnprocessableEntity},
+		{name: "missing token unaffected", configured: http.StatusForbidden, verifier: passingVerifier(), want: http.StatusBadRequest},
+		{name: "unavailable unaffected", configured: http.StatusForbidden, verifier: unavailableVerifier(), token: "token", want: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := NewMiddlewareWithConfig("", Config{
+				TurnstileVerifier:       tt.verifier,
+				ValidationFailureStatus: tt.configured,
+			})
+
+			if rec := serve(t, mw, newTokenRequest(tt.token)); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationFailureStatusNot4xxPanics(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusFound, http.StatusInternalServerError} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewMiddlewareWithConfig() with status %d did not panic", status)
+				}
+			}()
+
+			NewMiddlewareWithConfig("", Config{
+				TurnstileVerifier:       failingVerifier(),
+				ValidationFailureStatus: status,
+			})
+		})
+	}
+}
+
+func TestRequestHeaderRemoteIPExtractors(t *testing.T) {
+	tests := []struct {
+		name      string
+		extractor RemoteIPExtractorFunc
+		header    string
+	}{
+		{name: "CF-Connecting-IP", extractor: CloudFlareRequestHeaderRemoteIPExtractor(), header: DefaultRemoteIPHeader},
+		{name: "True-Client-IP", extractor: TrueClientIPRequestHeaderRemoteIPExtractor(), header: TrueClientIPHeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set(tt.header, "203.0.113.1")
+
+			got, err := tt.extractor(newContext(req))
+			if err != nil || got != "203.0.113.1" {
+				t.Errorf("extractor() = %q, %v, want %q", got, err, "203.0.113.1")
+			}
+
+			_, err = tt.extractor(newContext(httptest.NewRequest(http.MethodPost, "/", nil)))
+			var httpErr *echo.HTTPError
+			if !errors.As(err, &httpErr) || httpErr.Code != http.StatusBadRequest {
+				t.Errorf("extractor() without header error = %v, want 400", err)
+			}
+		})
+	}
+}
+
+func TestCloudFlareRemoteIPExtractor(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfIP         string
+		trueClientIP string
+		realIP       string
+		want         string
+	}{
+		{name: "CF-Connecting-IP first", cfIP: "203.0.113.1", trueClientIP: "203.0.113.2", realIP: "203.0.113.3", want: "203.0.113.1"},
+		{name: "True-Client-IP second", trueClientIP: "203.0.113.2", realIP: "203.0.113.3", want: "203.0.113.2"},
+		{name: "invalid CF-Connecting-IP skipped", cfIP: "unknown", trueClientIP: "203.0.113.2", want: "203.0.113.2"},
+		{name: "echo fallback", realIP: "203.0.113.3", want: "203.0.113.3"},
+		{name: "remote address fallback", want: "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			for header, value := range map[string]string{
+				DefaultRemoteIPHeader: tt.cfIP,
+				TrueClientIPHeader:    tt.trueClientIP,
+				echo.HeaderXRealIP:    tt.realIP,
+			} {
+				if value != "" {
+					req.Header.Set(header, value)
+				}
+			}
+
+			got, err := CloudFlareRemoteIPExtractor(newContext(req))
+			if err != nil || got != tt.want {
+				t.Errorf("CloudFlareRemoteIPExtractor() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMissingInputResponseIsBadRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		codes string
+		opts  []turnstile.Option
+		want  int
+	}{
+		{name: "missing input response", codes: `["missing-input-response"]`, want: http.StatusBadRequest},
+		{name: "collapsed errors", codes: `["missing-input-response"]`, opts: []turnstile.Option{turnstile.WithCollapseErrorsToValidationFailed()}, want: http.StatusBadRequest},
+		{name: "with other codes", codes: `["missing-input-response","internal-error"]`, want: http.StatusBadRequest},
+		{name: "invalid secret", codes: `["invalid-input-secret"]`, want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := turnstile.NewVerifierClientWithHandler("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"success":false,"error-codes":` + tt.codes + `}`))
+			}), tt.opts...)
+
+			mw := NewMiddlewareWithConfig("", Config{
+				TurnstileVerifier:       verifier,
+				ValidationFailureStatus: http.StatusForbidden,
+			})
+
+			rec := serve(t, mw, newTokenRequest("token"))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+type verificationContextKey struct{}
+
+func TestContextSetter(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier turnstile.Verifier
+		want     string
+	}{
+		{name: "verified", verifier: cdataVerifier("session"), want: "session"},
+		{name: "failed", verifier: failingVerifier()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			mw := NewMiddlewareWithConfig("", Config{
+				TurnstileVerifier: tt.verifier,
+				ContextSetter: func(c echo.Context, resp *turnstile.VerificationResponse) {
+					calls++
+					req := c.Request()
+					c.SetRequest(req.WithContext(context.WithValue(req.Context(), verificationContextKey{}, resp)))
+				},
+			})
+
+			var got string
+			e := echo.New()
+			e.POST("/", func(c echo.Context) error {
+				if resp, ok := c.Request().Context().Value(verificationContextKey{}).(*turnstile.VerificationResponse); ok {
+					got = resp.Cdata
+				}
+				return c.NoContent(http.StatusOK)
+			}, mw)
+			e.ServeHTTP(httptest.NewRecorder(), newTokenRequest("token"))
+
+			wantCalls := 0
+			if tt.want != "" {
+				wantCalls = 1
+			}
+			if calls != wantCalls {
+				t.Errorf("ContextSetter calls = %d, want %d", calls, wantCalls)
+			}
+			if got != tt.want {
+				t.Errorf("cdata from request context = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessLogger(t *testing.T) {
+	hostnameVerifier := verifierFunc(func(ctx context.Context, req *turnstile.VerificationRequest) (*turnstile.VerificationResponse, error) {
+		return &turnstile.VerificationResponse{Success: true, Hostname: "example.com"}, nil
+	})
+
+	tests := []struct {
+		name         string
+		verifier     turnstile.Verifier
+		skip         bool
+		wantLogged   bool
+		wantOutcome  string
+		wantHostname string
+	}{
+		{name: "verified", verifier: hostnameVerifier, wantLogged: true, wantOutcome: "verified", wantHostname: "example.com"},
+		{name: "failed", verifier: failingVerifier(), wantLogged: true, wantOutcome: "failed"},
+		{name: "skipped", verifier: hostnameVerifier, skip: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf strings.Builder
+			mw := NewMiddlewareWithConfig("secret", Config{
+				TurnstileVerifier: tt.verifier,
+				Skipper:           func(echo.Context) bool { return tt.skip },
+				AccessLogger:      slog.New(slog.NewJSONHandler(&buf, nil)),
+			})
+
+			req := newTokenRequest("secret-token")
+			req.URL.Path = "/signup"
+			serve(t, mw, req)
+
+			if !tt.wantLogged {
+				if buf.Len() != 0 {
+					t.Errorf("access log = %q, want nothing", buf.String())
+				}
+				return
+			}
+
+			var line map[string]any
+			if err := json.Unmarshal([]byte(buf.String()), &line); err != nil {
+				t.Fatalf("access log %q is not a single JSON line: %v", buf.String(), err)
+			}
+
+			want := map[string]any{"method": http.MethodPost, "path": "/signup", "outcome": tt.wantOutcome}
+			for key, value := range want {
+				if line[key] != value {
+					t.Errorf("access log %s = %v, want %v", key, line[key], value)
+				}
+			}
+			if hostname, _ := line["hostname"].(string); hostname != tt.wantHostname {
+				t.Errorf("access log hostname = %q, want %q", hostname, tt.wantHostname)
+			}
+			if _, ok := line["latency"]; !ok {
+				t.Error("access log lacks latency")
+			}
+			if strings.Contains(buf.String(), "secret") {
+				t.Errorf("access log %q contains the token or the secret", buf.String())
+			}
+		})
+	}
+}
+
+func TestChainIdempotencyKeyExtractor(t *testing.T) {
+	extractor := ChainIdempotencyKeyExtractor(
+		RequestHeaderIdempotencyKeyExtractor("X-Idempotency-Key"),
+		RequestHeaderIdempotencyKeyExtractor(echo.HeaderXRequestID),
+		GeneratingIdempotencyKeyExtractor,
+	)
+
+	tests := []struct {
+		name           string
+		idempotencyKey string
+		requestID      string
+		want           string
+	}{
+		{name: "idempotency key", idempotencyKey: "idem", requestID: "request", want: "idem"},
+		{name: "request ID", requestID: "request", want: "request"},
+		{name: "generated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.idempotencyKey != "" {
+				req.Header.Set("X-Idempotency-Key", tt.idempotencyKey)
+			}
+			if tt.requestID != "" {
+				req.Header.Set(echo.HeaderXRequestID, tt.requestID)
+			}
+
+			got, err := extractor(newContext(req))
+			if err != nil {
+				t.Fatalf("extractor() error = %v", err)
+			}
+
+			if tt.want == "" {
+				if got == "" {
+					t.Error("extractor() = \"\", want a generated key")
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("extractor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChainIdempotencyKeyExtractorErrors(t *testing.T) {
+	errExtract := errors.New("extract failed")
+	called := false
+
+	extractor := ChainIdempotencyKeyExtractor(
+		func(echo.Context) (string, error) { return "", errExtract },
+		func(echo.Context) (string, error) {
+			called = true
+			return "key", nil
+		},
+	)
+
+	if _, err := extractor(newContext(httptest.NewRequest(http.MethodPost, "/", nil))); !errors.Is(err, errExtract) {
+		t.Errorf("extractor() error = %v, want %v", err, errExtract)
+	}
+	if called {
+		t.Error("extractor after the failing one was called")
+	}
+}
+
+func TestChainIdempotencyKeyExtractorAllEmpty(t *testing.T) {
+	extractor := ChainIdempotencyKeyExtractor(RequestHeaderIdempotencyKeyExtractor("X-Idempotency-Key"))
+
+	got, err := extractor(newContext(httptest.NewRequest(http.MethodPost, "/", nil)))
+	if got != "" || err != nil {
+		t.Errorf("extractor() = %q, %v, want an empty key", got, err)
+	}
+}
+
+func TestVerifyOnlyIfTokenPresent(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier turnstile.Verifier
+		token    string
+		status   int
+		calls    int
+		outcome  Outcome
+	}{
+		{name: "absent", verifier: failingVerifier(), status: http.StatusOK, outcome: OutcomeSkipped},
+		{name: "present and valid", verifier: passingVerifier(), token: "token", status: http.StatusOK, calls: 1, outcome: OutcomeVerified},
+		{name: "present and invalid", verifier: failingVerifier(), token: "token", status: http.StatusBadRequest, calls: 1, outcome: OutcomeFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			var outcomes []Outcome
+			mw := NewMiddlewareWithConfig("", Config{
+				TurnstileVerifier:        countingVerifier(tt.verifier, &calls),
+				VerifyOnlyIfTokenPresent: true,
+				MetricsHook: func(c echo.Context, outcome Outcome) {
+					outcomes = append(outcomes, outcome)
+				},
+			})
+
+			rec := serve(t, mw, newTokenRequest(tt.token))
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if calls != tt.calls {
+				t.Errorf("verifier calls = %d, want %d", calls, tt.calls)
+			}
+			if len(outcomes) != 1 || outcomes[0] != tt.outcome {
+				t.Errorf("outcomes = %v, want [%v]", outcomes, tt.outcome)
+			}
+		})
+	}
+}
+
+func TestVerifyOnlyIfTokenPresentExtractorError(t *testing.T) {
+	errExtract := echo.NewHTTPError(http.StatusRequestEntityTooLarge)
+	var calls int
+	mw := NewMiddlewareWithConfig("", Config{
+		TurnstileVerifier: countingVerifier(passingVerifier(), &calls),
+		TurnstileResponseExtractorFunc: func(echo.Context) (string, error) {
+			return "", errExtract
+		},
+		VerifyOnlyIfTokenPresent: true,
+	})
+
+	if rec := serve(t, mw, newTokenRequest("")); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+	if calls != 0 {
+		t.Errorf("verifier calls = %d, want 0", calls)
+	}
+}
+
+func TestTooManyInFlightIsServiceUnavailable(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	verifier := turnstile.NewFailFastBoundedVerifier(verifierFunc(func(ctx context.Context, req *turnstile.VerificationRequest) (*turnstile.VerificationResponse, error) {
+		close(started)
+		<-release
+		return &turnstile.VerificationResponse{Success: true}, nil
+	}), 1)
+
+	mw := NewMiddlewareWithConfig("", Config{TurnstileVerifier: verifier})
+
+	done := make(chan int)
+	go func() {
+		done <- serve(t, mw, newTokenRequest("first")).Code
+	}()
+	<-started
+
+	rec := serve(t, mw, newTokenRequest("second"))
+	close(release)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if got := rec.Header().Get(echo.HeaderRetryAfter); got != loadSheddingRetryAfter {
+		t.Errorf("Retry-After = %q, want %q", got, loadSheddingRetryAfter)
+	}
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("first request status = %d, want %d", code, http.StatusOK)
+	}
+}
@@ -0,0 +1,99 @@
+package echoturnstile
+
+import (
+	"errors"
+	"time"
+
+	"github.com/binhatch/go-turnstile/turnstile"
+	"github.com/labstack/echo/v4"
+)
+
+// MultiTokenPolicy decides how many tokens of a multi-widget request must pass.
+type MultiTokenPolicy int
+
+const (
+	// MultiTokenPolicyAll requires every token to pass.
+	MultiTokenPolicyAll MultiTokenPolicy = iota
+	// MultiTokenPolicyAny requires at least one token to pass.
+	MultiTokenPolicyAny
+)
+
+// MultiTokenExtractorFunc extracts all tokens submitted with a request, e.g.
+// by a page with several widgets.
+type MultiTokenExtractorFunc func(c echo.Context) ([]string, error)
+
+type requestHeaderMultiTokenExtractor struct {
+	headerName string
+}
+
+func RequestHeaderMultiTokenExtractorFunc() MultiTokenExtractorFunc {
+	return RequestHeaderMultiTokenExtractorFuncWithHeaderName(DefaultTurnstileResponseHeader)
+}
+
+// RequestHeaderMultiTokenExtractorFuncWithHeaderName returns every value of
+// the named header as a token.
+func RequestHeaderMultiTokenExtractorFuncWithHeaderName(headerName string) MultiTokenExtractorFunc {
+	return (&requestHeaderMultiTokenExtractor{headerName: headerName}).Extract
+}
+
+func (e *requestHeaderMultiTokenExtractor) Extract(c echo.Context) ([]string, error) {
+	var tokens []string
+	for _, val := range c.Request().Header.Values(e.headerName) {
+		if val != "" {
+			tokens = append(tokens, val)
+		}
+	}
+
+	return tokens, nil
+}
+
+// verifyMulti verifies every extracted token and combines the failures into a
+// single error. Idempotency keys are not sent, as Cloudflare would treat the
+// tokens sharing one key as duplicates.
+func (mw *middleware) verifyMulti(c echo.Context) (*turnstile.VerificationResponse, error) {
+	tokens, err := mw.multiTokenExtractorFunc(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, missingTokenError("expected turnstile responses")
+	}
+
+	remoteIP, err := mw.remoteIPExtractorFunc(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		passed *turnstile.VerificationResponse
+		errs   []error
+		start  = time.Now()
+	)
+
+	for _, token := range tokens {
+		req := &turnstile.VerificationRequest{
+			Response: token,
+			RemoteIP: remoteIP,
+		}
+
+		resp, err := mw.turnstileVerifier.Verify(c.Request().Context(), req)
+		if err == nil {
+			err = mw.checkResponse(c, resp)
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+		} else if passed == nil {
+			passed = resp
+		}
+	}
+
+	c.Set(VerifyDurationContextKey, time.Since(start))
+
+	if passed != nil && (len(errs) == 0 || mw.multiTokenPolicy == MultiTokenPolicyAny) {
+		return passed, nil
+	}
+
+	return nil, errors.Join(errs...)
+}
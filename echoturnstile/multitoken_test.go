@@ -0,0 +1,83 @@
+package echoturnstile
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/binhatch/go-turnstile/turnstile"
+	"github.com/labstack/echo/v4"
+)
+
+// tokenVerifier passes the token "good" and rejects every other one.
+func tokenVerifier(verified *[]string) turnstile.Verifier {
+	return verifierFunc(func(ctx context.Context, req *turnstile.VerificationRequest) (*turnstile.VerificationResponse, error) {
+		*verified = append(*verified, req.Response)
+		if req.Response == "good" {
+			return passingVerifier().Verify(ctx, req)
+		}
+		return failingVerifier().Verify(ctx, req)
+	})
+}
+
+func TestMultiToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy MultiTokenPolicy
+		tokens []string
+		status int
+	}{
+		{name: "all pass", policy: MultiTokenPolicyAll, tokens: []string{"good", "good"}, status: http.StatusOK},
+		{name: "all with one failing", policy: MultiTokenPolicyAll, tokens: []string{"good", "bad"}, status: http.StatusBadRequest},
+		{name: "any with one failing", policy: MultiTokenPolicyAny, tokens: []string{"bad", "good"}, status: http.StatusOK},
+		{name: "any with all failing", policy: MultiTokenPolicyAny, tokens: []string{"bad", "bad"}, status: http.StatusBadRequest},
+		{name: "no tokens", policy: MultiTokenPolicyAny, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var verified []string
+			mw := NewMiddlewareWithConfig("", Config{
+				TurnstileVerifier: tokenVerifier(&verified),
+				MultiToken:        true,
+				MultiTokenPolicy:  tt.policy,
+			})
+
+			req := newTokenRequest("")
+			for _, token := range tt.tokens {
+				req.Header.Add(DefaultTurnstileResponseHeader, token)
+			}
+
+			if rec := serve(t, mw, req); rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if len(verified) != len(tt.tokens) {
+				t.Errorf("verified tokens = %q, want all of %q", verified, tt.tokens)
+			}
+		})
+	}
+}
+
+func TestMultiTokenAggregatesFailures(t *testing.T) {
+	var verified []string
+	mw := NewMiddlewareWithConfig("", Config{
+		TurnstileVerifier: tokenVerifier(&verified),
+		MultiToken:        true,
+	})
+
+	req := newTokenRequest("")
+	req.Header.Add(DefaultTurnstileResponseHeader, "bad")
+	req.Header.Add(DefaultTurnstileResponseHeader, "worse")
+
+	err := mw(okHandler)(newContext(req))
+
+	httpErr, ok := err.(*echo.HTTPError)
+	if !ok {
+		t.Fatalf("error = %#v, want *echo.HTTPError", err)
+	}
+
+	joined, ok := httpErr.Internal.(interface{ Unwrap() []error })
+	if !ok || len(joined.Unwrap()) != 2 {
+		t.Errorf("Internal = %v, want both failures", httpErr.Internal)
+	}
+}
@@ -0,0 +1,51 @@
+package echoturnstile
+
+import (
+	"errors"
+
+	"github.com/binhatch/go-turnstile/turnstile"
+	"github.com/labstack/echo/v4"
+)
+
+// Outcome describes how the middleware handled a single request.
+type Outcome int
+
+const (
+	// OutcomeSkipped means the Skipper bypassed verification.
+	OutcomeSkipped Outcome = iota
+	// OutcomeVerified means the token was verified and the request passed.
+	OutcomeVerified
+	// OutcomeFailed means Cloudflare rejected the token.
+	OutcomeFailed
+	// OutcomeError means verification could not be completed, e.g. a missing
+	// token, an extractor failure or an unreachable siteverify endpoint.
+	OutcomeError
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSkipped:
+		return "skipped"
+	case OutcomeVerified:
+		return "verified"
+	case OutcomeFailed:
+		return "failed"
+	case OutcomeError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+type MetricsHookFunc func(c echo.Context, outcome Outcome)
+
+func outcomeOf(err error) Outcome {
+	switch {
+	case err == nil:
+		return OutcomeVerified
+	case errors.Is(err, turnstile.ErrValidationFailed):
+		return OutcomeFailed
+	default:
+		return OutcomeError
+	}
+}
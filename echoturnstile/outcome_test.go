@@ -0,0 +1,100 @@
+package echoturnstile
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/binhatch/go-turnstile/turnstile"
+	"github.com/labstack/echo/v4"
+)
+
+func TestMetricsHookOutcome(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier turnstile.Verifier
+		skip     bool
+		token    string
+		want     Outcome
+		status   int
+	}{
+		{name: "skipped", verifier: failingVerifier(), skip: true, token: "token", want: OutcomeSkipped, status: http.StatusOK},
+		{name: "verified", verifier: passingVerifier(), token: "token", want: OutcomeVerified, status: http.StatusOK},
+		{name: "failed", verifier: failingVerifier(), token: "token", want: OutcomeFailed, status: http.StatusBadRequest},
+		{name: "missing token", verifier: passingVerifier(), want: OutcomeError, status: http.StatusBadRequest},
+		{name: "unavailable", verifier: unavailableVerifier(), token: "token", want: OutcomeError, status: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Outcome
+			mw := NewMiddlewareWithConfig("", Config{
+				TurnstileVerifier: tt.verifier,
+				Skipper:           func(echo.Context) bool { return tt.skip },
+				MetricsHook: func(c echo.Context, outcome Outcome) {
+					got = append(got, outcome)
+				},
+			})
+
+			rec := serve(t, mw, newTokenRequest(tt.token))
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("outcomes = %v, want [%v]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutcomeString(t *testing.T) {
+	for outcome, want := range map[Outcome]string{
+		OutcomeSkipped:  "skipped",
+		OutcomeVerified: "verified",
+		OutcomeFailed:   "failed",
+		OutcomeError:    "error",
+		Outcome(42):     "unknown",
+	} {
+		if got := outcome.String(); got != want {
+			t.Errorf("Outcome(%d).String() = %q, want %q", int(outcome), got, want)
+		}
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier turnstile.Verifier
+		token    string
+		want     Outcome
+	}{
+		{name: "verified", verifier: passingVerifier(), token: "token", want: OutcomeVerified},
+		{name: "failed", verifier: failingVerifier(), token: "token", want: OutcomeFailed},
+		{name: "missing token", verifier: passingVerifier(), want: OutcomeError},
+		{name: "unavailable", verifier: unavailableVerifier(), token: "token", want: OutcomeError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Outcome
+			mw := NewMiddlewareWithConfig("", Config{
+				TurnstileVerifier: tt.verifier,
+				Monitor:           true,
+				MetricsHook: func(c echo.Context, outcome Outcome) {
+					got = append(got, outcome)
+				},
+			})
+
+			rec := serve(t, mw, newTokenRequest(tt.token))
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("outcomes = %v, want [%v]", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package echoturnstile
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/binhatch/go-turnstile/turnstile"
+	"github.com/labstack/echo/v4"
+)
+
+const mimeApplicationProblemJSON = "application/problem+json"
+
+// Problem is an RFC 7807 Problem Details document describing a failed
+// verification.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Code classifies the failure, e.g. "missing_response" or
+	// "validation_failure".
+	Code string `json:"code"`
+	// ErrorCodes lists the codes reported by Cloudflare, if any.
+	ErrorCodes []turnstile.ErrorCode `json:"error-codes,omitempty"`
+}
+
+// ProblemJSONErrorHandler renders middleware failures as
+// application/problem+json. Use it as Config.ErrorHandler.
+func ProblemJSONErrorHandler(c echo.Context, err error) error {
+	httpErr, ok := err.(*echo.HTTPError)
+	if !ok {
+		httpErr = echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	problem := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(httpErr.Code),
+		Status: httpErr.Code,
+		Code:   turnstile.KindOf(err).String(),
+	}
+
+	if detail, ok := httpErr.Message.(string); ok && detail != problem.Title {
+		problem.Detail = detail
+	}
+
+	if errors.Is(err, ErrMissingTurnstileResponse) {
+		problem.Code = "missing_response"
+	}
+
+	var verr *turnstile.VerificationError
+	if errors.As(err, &verr) {
+		problem.ErrorCodes = verr.ErrorCodes
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, mimeApplicationProblemJSON)
+	return c.JSON(httpErr.Code, problem)
+}
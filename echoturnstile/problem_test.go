@@ -0,0 +1,65 @@
+package echoturnstile
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/binhatch/go-turnstile/turnstile"
+)
+
+func TestProblemJSONErrorHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		verifier   turnstile.Verifier
+		token      string
+		status     int
+		code       string
+		errorCodes []turnstile.ErrorCode
+	}{
+		{
+			name:       "validation failure",
+			verifier:   failingVerifier(),
+			token:      "token",
+			status:     http.StatusBadRequest,
+			code:       "validation_failure",
+			errorCodes: []turnstile.ErrorCode{turnstile.CodeInvalidInputResponse},
+		},
+		{name: "missing token", verifier: passingVerifier(), status: http.StatusBadRequest, code: "missing_response"},
+		{name: "unavailable", verifier: unavailableVerifier(), token: "token", status: http.StatusBadGateway, code: "transport_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := NewMiddlewareWithConfig("", Config{
+				TurnstileVerifier: tt.verifier,
+				ErrorHandler:      ProblemJSONErrorHandler,
+			})
+
+			rec := serve(t, mw, newTokenRequest(tt.token))
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Content-Type"); got != mimeApplicationProblemJSON {
+				t.Errorf("Content-Type = %q, want %q", got, mimeApplicationProblemJSON)
+			}
+
+			var problem Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("decoding problem %s: %v", rec.Body, err)
+			}
+
+			if problem.Type != "about:blank" || problem.Title != http.StatusText(tt.status) || problem.Status != tt.status {
+				t.Errorf("problem = %+v, want type about:blank, title and status %d", problem, tt.status)
+			}
+			if problem.Code != tt.code {
+				t.Errorf("problem code = %q, want %q", problem.Code, tt.code)
+			}
+			if !slices.Equal(problem.ErrorCodes, tt.errorCodes) {
+				t.Errorf("problem error codes = %v, want %v", problem.ErrorCodes, tt.errorCodes)
+			}
+		})
+	}
+}
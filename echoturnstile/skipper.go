@@ -0,0 +1,54 @@
+package echoturnstile
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	echomiddleware "github.com/labstack/echo/v4/middleware"
+)
+
+// HeaderThresholdSkipper skips verification for requests whose numeric header
+// value, e.g. a risk score set by an upstream WAF, is below threshold or
+// absent. Values that can not be parsed are verified.
+func HeaderThresholdSkipper(header string, threshold int) echomiddleware.Skipper {
+	return func(c echo.Context) bool {
+		val := strings.TrimSpace(c.Request().Header.Get(header))
+		if val == "" {
+			return true
+		}
+
+		score, err := strconv.Atoi(val)
+		if err != nil {
+			return false
+		}
+
+		return score < threshold
+	}
+}
+
+// CombineSkippers returns a Skipper that skips when any of skippers does.
+func CombineSkippers(skippers ...echomiddleware.Skipper) echomiddleware.Skipper {
+	return func(c echo.Context) bool {
+		for _, skipper := range skippers {
+			if skipper(c) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// AllSkippers returns a Skipper that skips only when all of skippers do.
+func AllSkippers(skippers ...echomiddleware.Skipper) echomiddleware.Skipper {
+	return func(c echo.Context) bool {
+		for _, skipper := range skippers {
+			if !skipper(c) {
+				return false
+			}
+		}
+
+		return len(skippers) > 0
+	}
+}
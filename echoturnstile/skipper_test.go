@@ -0,0 +1,110 @@
+package echoturnstile
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	echomiddleware "github.com/labstack/echo/v4/middleware"
+)
+
+func TestHeaderThresholdSkipper(t *testing.T) {
+	const header = "X-Risk-Score"
+
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{name: "below", value: "10", want: true},
+		{name: "negative", value: "-5", want: true},
+		{name: "at threshold", value: "50", want: false},
+		{name: "above", value: "90", want: false},
+		{name: "surrounding whitespace", value: " 10 ", want: true},
+		{name: "missing", value: "", want: true},
+		{name: "invalid", value: "high", want: false},
+		{name: "fractional", value: "10.5", want: false},
+	}
+
+	skipper := HeaderThresholdSkipper(header, 50)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.value != "" {
+				req.Header.Set(header, tt.value)
+			}
+
+			if got := skipper(newContext(req)); got != tt.want {
+				t.Errorf("skipper(%s: %q) = %v, want %v", header, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCombineSkippers(t *testing.T) {
+	skip := func(echo.Context) bool { return true }
+	verify := func(echo.Context) bool { return false }
+
+	tests := []struct {
+		name     string
+		skippers []echomiddleware.Skipper
+		wantAny  bool
+		wantAll  bool
+	}{
+		{name: "none"},
+		{name: "one skips", skippers: []echomiddleware.Skipper{skip}, wantAny: true, wantAll: true},
+		{name: "one verifies", skippers: []echomiddleware.Skipper{verify}},
+		{name: "mixed", skippers: []echomiddleware.Skipper{verify, skip}, wantAny: true},
+		{name: "all skip", skippers: []echomiddleware.Skipper{skip, skip}, wantAny: true, wantAll: true},
+	}
+
+	c := newContext(httptest.NewRequest(http.MethodGet, "/", nil))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CombineSkippers(tt.skippers...)(c); got != tt.wantAny {
+				t.Errorf("CombineSkippers() = %v, want %v", got, tt.wantAny)
+			}
+			if got := AllSkippers(tt.skippers...)(c); got != tt.wantAll {
+				t.Errorf("AllSkippers() = %v, want %v", got, tt.wantAll)
+			}
+		})
+	}
+}
+
+func TestCombineSkippersOnGroup(t *testing.T) {
+	e := echo.New()
+	g := e.Group("/api", Protect(failingVerifier(), func(cfg *Config) {
+		cfg.Skipper = CombineSkippers(
+			func(c echo.Context) bool { return c.Path() == "/api/health" },
+			func(c echo.Context) bool { return c.Request().Method == http.MethodOptions },
+		)
+	}))
+	g.Any("/*", okHandler)
+	g.GET("/health", okHandler)
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{method: http.MethodGet, path: "/api/health", status: http.StatusOK},
+		{method: http.MethodOptions, path: "/api/users", status: http.StatusOK},
+		{method: http.MethodPost, path: "/api/users", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req := newTokenRequest("token")
+		req.Method = tt.method
+		req.URL.Path = tt.path
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s %s status = %d, want %d", tt.method, tt.path, rec.Code, tt.status)
+		}
+	}
+}
@@ -2,7 +2,10 @@ module github.com/binhatch/go-turnstile
 
 go 1.21.3
 
-require github.com/labstack/echo/v4 v4.11.2
+require (
+	github.com/labstack/echo/v4 v4.11.2
+	go.uber.org/goleak v1.3.0
+)
 
 require (
 	github.com/golang-jwt/jwt v3.2.2+incompatible // indirect
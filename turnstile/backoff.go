@@ -0,0 +1,112 @@
+package turnstile
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Backoff computes the delay before the given retry attempt, counting from 1
+// for the delay after the first failed call.
+type Backoff interface {
+	Next(attempt int) time.Duration
+}
+
+type constantBackoff struct {
+	delay time.Duration
+}
+
+// ConstantBackoff waits delay before every retry.
+func ConstantBackoff(delay time.Duration) Backoff {
+	return constantBackoff{delay: delay}
+}
+
+func (b constantBackoff) Next(int) time.Duration {
+	return b.delay
+}
+
+type linearBackoff struct {
+	step     time.Duration
+	maxDelay time.Duration
+}
+
+// LinearBackoff waits step times the attempt number, capped at maxDelay.
+func LinearBackoff(step, maxDelay time.Duration) Backoff {
+	return linearBackoff{step: step, maxDelay: maxDelay}
+}
+
+func (b linearBackoff) Next(attempt int) time.Duration {
+	delay := b.step * time.Duration(attempt)
+	if attempt > 0 && delay/time.Duration(attempt) != b.step {
+		return b.maxDelay
+	}
+
+	return min(delay, b.maxDelay)
+}
+
+type exponentialBackoff struct {
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+// ExponentialBackoff doubles the delay with every attempt, starting from
+// baseDelay and capped at maxDelay. It is the default of WithRetry.
+func ExponentialBackoff(baseDelay, maxDelay time.Duration) Backoff {
+	return exponentialBackoff{baseDelay: baseDelay, maxDelay: maxDelay}
+}
+
+func (b exponentialBackoff) Next(attempt int) time.Duration {
+	delay := b.baseDelay << (attempt - 1)
+	if delay < b.baseDelay {
+		delay = b.maxDelay
+	}
+
+	return min(delay, b.maxDelay)
+}
+
+type decorrelatedJitterBackoff struct {
+	baseDelay time.Duration
+	maxDelay  time.Duration
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// DecorrelatedJitterBackoff picks each delay at random between baseDelay and
+// an upper bound growing threefold with every attempt, capped at maxDelay,
+// spreading out retries of concurrent callers. A Backoff is shared by every
+// verification of a client, so the bound is derived from the attempt rather
+// than from the previous delay of the same caller. rng makes the delays
+// reproducible; nil seeds a generator from the current time.
+func DecorrelatedJitterBackoff(baseDelay, maxDelay time.Duration, rng *rand.Rand) Backoff {
+	if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	return &decorrelatedJitterBackoff{
+		baseDelay: baseDelay,
+		maxDelay:  maxDelay,
+		rng:       rng,
+	}
+}
+
+func (b *decorrelatedJitterBackoff) Next(attempt int) time.Duration {
+	upper := b.baseDelay
+	for i := 0; i < attempt; i++ {
+		if upper > b.maxDelay/3 {
+			upper = b.maxDelay
+			break
+		}
+
+		upper *= 3
+	}
+
+	delay := b.baseDelay
+	if spread := min(upper, b.maxDelay) - b.baseDelay; spread > 0 {
+		b.mu.Lock()
+		delay += time.Duration(b.rng.Int63n(int64(spread)))
+		b.mu.Unlock()
+	}
+
+	return min(delay, b.maxDelay)
+}
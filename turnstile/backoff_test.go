@@ -0,0 +1,124 @@
+package turnstile
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func backoffDelays(b Backoff, attempts int) []time.Duration {
+	delays := make([]time.Duration, attempts)
+	for i := range delays {
+		delays[i] = b.Next(i + 1)
+	}
+
+	return delays
+}
+
+func TestBackoffDelays(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+		want    []time.Duration
+	}{
+		{
+			name:    "constant",
+			backoff: ConstantBackoff(100 * time.Millisecond),
+			want:    []time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond},
+		},
+		{
+			name:    "linear",
+			backoff: LinearBackoff(100*time.Millisecond, 350*time.Millisecond),
+			want:    []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 350 * time.Millisecond},
+		},
+		{
+			name:    "exponential",
+			backoff: ExponentialBackoff(100*time.Millisecond, 500*time.Millisecond),
+			want:    []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 500 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := backoffDelays(tt.backoff, len(tt.want)); !slices.Equal(got, tt.want) {
+				t.Errorf("delays = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffOverflowCapped(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+		attempt int
+	}{
+		{name: "linear", backoff: LinearBackoff(time.Hour, time.Minute), attempt: math.MaxInt},
+		{name: "exponential", backoff: ExponentialBackoff(time.Second, time.Minute), attempt: 100},
+		{name: "decorrelated jitter", backoff: DecorrelatedJitterBackoff(time.Second, time.Minute, rand.New(rand.NewSource(1))), attempt: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.backoff.Next(tt.attempt); got <= 0 || got > time.Minute {
+				t.Errorf("Next(%d) = %v, want within (0, 1m]", tt.attempt, got)
+			}
+		})
+	}
+}
+
+func TestDecorrelatedJitterBackoff(t *testing.T) {
+	const (
+		base     = 100 * time.Millisecond
+		maxDelay = 5 * time.Second
+	)
+
+	b := DecorrelatedJitterBackoff(base, maxDelay, rand.New(rand.NewSource(42)))
+
+	upper := base
+	for attempt := 1; attempt <= 8; attempt++ {
+		upper = min(upper*3, maxDelay)
+
+		for i := 0; i < 100; i++ {
+			if got := b.Next(attempt); got < base || got > upper {
+				t.Fatalf("Next(%d) = %v, want within [%v, %v]", attempt, got, base, upper)
+			}
+		}
+	}
+}
+
+func TestDecorrelatedJitterBackoffSeeded(t *testing.T) {
+	first := backoffDelays(DecorrelatedJitterBackoff(100*time.Millisecond, 5*time.Second, rand.New(rand.NewSource(7))), 6)
+	second := backoffDelays(DecorrelatedJitterBackoff(100*time.Millisecond, 5*time.Second, rand.New(rand.NewSource(7))), 6)
+
+	if !slices.Equal(first, second) {
+		t.Errorf("delays with the same seed = %v and %v, want them equal", first, second)
+	}
+}
+
+func TestDecorrelatedJitterBackoffWithoutSpread(t *testing.T) {
+	b := DecorrelatedJitterBackoff(time.Second, time.Second, nil)
+
+	if got := backoffDelays(b, 3); !slices.Equal(got, []time.Duration{time.Second, time.Second, time.Second}) {
+		t.Errorf("delays = %v, want the base delay", got)
+	}
+}
+
+func TestWithBackoff(t *testing.T) {
+	v := NewVerifierClient("secret",
+		WithRetry(3, 100*time.Millisecond, time.Second),
+		WithBackoff(LinearBackoff(400*time.Millisecond, time.Minute)),
+	).(*verifierClient)
+
+	err := transportError(&HTTPStatusError{StatusCode: http.StatusServiceUnavailable})
+
+	want := []time.Duration{400 * time.Millisecond, 800 * time.Millisecond, time.Second}
+	for i, want := range want {
+		if got := v.retryDelay(i+1, err); got != want {
+			t.Errorf("retryDelay(%d) = %v, want %v", i+1, got, want)
+		}
+	}
+}
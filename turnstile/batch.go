@@ -0,0 +1,94 @@
+package turnstile
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const maxBatchLineBytes = 1 << 20
+
+// BatchResult is the outcome of verifying one line of a batch.
+type BatchResult struct {
+	// Line is the 1-based line number of the request in the input.
+	Line     int
+	Request  *VerificationRequest
+	Response *VerificationResponse
+	Err      error
+}
+
+// DefaultBatchConcurrency is the number of verifications VerifyFromReader runs
+// at once unless set with WithBatchConcurrency.
+const DefaultBatchConcurrency = 8
+
+// BatchVerifier is implemented by the Verifier returned by the constructors,
+// for admin tools validating collected tokens in bulk through a type
+// assertion.
+type BatchVerifier interface {
+	VerifyFromReader(ctx context.Context, r io.Reader) ([]BatchResult, error)
+}
+
+// VerifyFromReader reads newline-delimited JSON VerificationRequests from r and
+// verifies each, running at most the number of verifications set with
+// WithBatchConcurrency at once. Results are returned in input order, one per
+// non-empty line; lines that can not be decoded get a result with Err set. The
+// returned error is only set if reading r fails.
+func (t *verifierClient) VerifyFromReader(ctx context.Context, r io.Reader) ([]BatchResult, error) {
+	return verifyFromReader(ctx, t, r, t.batchConcurrency)
+}
+
+func verifyFromReader(ctx context.Context, v Verifier, r io.Reader, maxConcurrency int) ([]BatchResult, error) {
+	var (
+		results []*BatchResult
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, max(maxConcurrency, 1))
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBatchLineBytes)
+
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		result := &BatchResult{Line: line}
+		results = append(results, result)
+
+		req := &VerificationRequest{}
+		if err := json.Unmarshal(data, req); err != nil {
+			result.Err = fmt.Errorf("can not decode verification request on line %d: %w", line, err)
+			continue
+		}
+		result.Request = req
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			result.Response, result.Err = v.Verify(ctx, req)
+		}()
+	}
+
+	wg.Wait()
+
+	out := make([]BatchResult, len(results))
+	for i, result := range results {
+		out[i] = *result
+	}
+
+	if err := scanner.Err(); err != nil {
+		return out, fmt.Errorf("can not read verification requests: %w", err)
+	}
+
+	return out, nil
+}
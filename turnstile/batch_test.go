@@ -0,0 +1,142 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestVerifyFromReader(t *testing.T) {
+	input := strings.Join([]string{
+		`{"response":"good","remoteip":"203.0.113.1"}`,
+		`{"response":"bad"}`,
+		``,
+		`not json`,
+		`{"response":"good"}`,
+	}, "\n")
+
+	v := verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+		if req.Response != "good" {
+			return &VerificationResponse{ErrorCodes: []ErrorCode{CodeInvalidInputResponse}},
+				mapErrorCodes([]ErrorCode{CodeInvalidInputResponse})
+		}
+		return &VerificationResponse{Success: true}, nil
+	})
+
+	results, err := verifyFromReader(context.Background(), v, strings.NewReader(input), 2)
+	if err != nil {
+		t.Fatalf("VerifyFromReader() error = %v", err)
+	}
+
+	want := []struct {
+		line    int
+		success bool
+		kind    OutcomeKind
+	}{
+		{line: 1, success: true, kind: OutcomeKindSuccess},
+		{line: 2, kind: OutcomeKindValidationFailure},
+		{line: 4, kind: OutcomeKindUnknown},
+		{line: 5, success: true, kind: OutcomeKindSuccess},
+	}
+
+	if len(results) != len(want) {
+		t.Fatalf("VerifyFromReader() returned %d results, want %d", len(results), len(want))
+	}
+
+	for i, w := range want {
+		got := results[i]
+		if got.Line != w.line {
+			t.Errorf("results[%d].Line = %d, want %d", i, got.Line, w.line)
+		}
+		if KindOf(got.Err) != w.kind {
+			t.Errorf("results[%d].Err = %v, want kind %v", i, got.Err, w.kind)
+		}
+		if success := got.Response != nil && got.Response.Success; success != w.success {
+			t.Errorf("results[%d] success = %v, want %v", i, success, w.success)
+		}
+	}
+
+	if results[0].Request == nil || results[0].Request.RemoteIP != "203.0.113.1" {
+		t.Errorf("results[0].Request = %+v, want the decoded request", results[0].Request)
+	}
+	if results[2].Request != nil {
+		t.Errorf("results[2].Request = %+v for an undecodable line, want nil", results[2].Request)
+	}
+}
+
+func TestVerifyFromReaderConcurrency(t *testing.T) {
+	const maxConcurrency = 2
+
+	var inFlight, peak atomic.Int32
+	v := verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+
+		time.Sleep(time.Millisecond)
+		return &VerificationResponse{Success: true}, nil
+	})
+
+	input := strings.Repeat(`{"response":"token"}`+"\n", 10)
+	results, err := verifyFromReader(context.Background(), v, strings.NewReader(input), maxConcurrency)
+	if err != nil || len(results) != 10 {
+		t.Fatalf("VerifyFromReader() = %d results, %v, want 10 results", len(results), err)
+	}
+
+	if got := peak.Load(); got > maxConcurrency {
+		t.Errorf("peak concurrent verifications = %d, want at most %d", got, maxConcurrency)
+	}
+}
+
+func TestVerifyFromReaderReadError(t *testing.T) {
+	errRead := errors.New("read failed")
+
+	_, err := NewVerifierClient("secret").(BatchVerifier).VerifyFromReader(context.Background(), iotest.ErrReader(errRead))
+	if !errors.Is(err, errRead) {
+		t.Errorf("VerifyFromReader() error = %v, want %v", err, errRead)
+	}
+}
+
+func TestVerifierClientVerifyFromReader(t *testing.T) {
+	const maxConcurrency = 2
+
+	var inFlight, peak atomic.Int32
+	v := NewVerifierClientWithHandler("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+
+		time.Sleep(time.Millisecond)
+		respondJSON(`{"success":true}`)(w, r)
+	}), WithBatchConcurrency(maxConcurrency))
+
+	batch, ok := v.(BatchVerifier)
+	if !ok {
+		t.Fatalf("%T does not implement BatchVerifier", v)
+	}
+
+	input := strings.Repeat(`{"response":"token"}`+"\n", 10)
+	results, err := batch.VerifyFromReader(context.Background(), strings.NewReader(input))
+	if err != nil || len(results) != 10 {
+		t.Fatalf("VerifyFromReader() = %d results, %v, want 10 results", len(results), err)
+	}
+
+	for i, result := range results {
+		if result.Err != nil || !result.Response.Success {
+			t.Errorf("results[%d] = %+v, want success", i, result)
+		}
+	}
+
+	if got := peak.Load(); got > maxConcurrency {
+		t.Errorf("peak concurrent verifications = %d, want at most %d", got, maxConcurrency)
+	}
+}
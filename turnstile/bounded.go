@@ -0,0 +1,64 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var ErrTooManyInFlight = errors.New("too many verifications in flight")
+
+type boundedVerifier struct {
+	inner    Verifier
+	sem      chan struct{}
+	failFast bool
+}
+
+// NewBoundedVerifier limits the number of concurrent calls to inner to
+// maxInFlight. Calls beyond the limit wait for a free slot or until their
+// context is done.
+func NewBoundedVerifier(inner Verifier, maxInFlight int) Verifier {
+	return newBoundedVerifier(inner, maxInFlight, false)
+}
+
+// NewFailFastBoundedVerifier limits the number of concurrent calls to inner to
+// maxInFlight, failing calls beyond the limit with ErrTooManyInFlight instead
+// of waiting.
+func NewFailFastBoundedVerifier(inner Verifier, maxInFlight int) Verifier {
+	return newBoundedVerifier(inner, maxInFlight, true)
+}
+
+func newBoundedVerifier(inner Verifier, maxInFlight int, failFast bool) *boundedVerifier {
+	return &boundedVerifier{
+		inner:    inner,
+		sem:      make(chan struct{}, max(maxInFlight, 1)),
+		failFast: failFast,
+	}
+}
+
+func (v *boundedVerifier) Verify(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+	if err := v.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer func() { <-v.sem }()
+
+	return v.inner.Verify(ctx, req)
+}
+
+func (v *boundedVerifier) acquire(ctx context.Context) error {
+	if v.failFast {
+		select {
+		case v.sem <- struct{}{}:
+			return nil
+		default:
+			return ErrTooManyInFlight
+		}
+	}
+
+	select {
+	case v.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for a verification slot: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,100 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBoundedVerifierCap(t *testing.T) {
+	const maxInFlight = 3
+
+	var inFlight, peak atomic.Int32
+	v := NewBoundedVerifier(verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+		return &VerificationResponse{Success: true}, nil
+	}), maxInFlight)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := verifyToken(v); err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got < 1 || got > maxInFlight {
+		t.Errorf("peak in-flight calls = %d, want between 1 and %d", got, maxInFlight)
+	}
+}
+
+// blockingVerifier returns a verifier whose calls signal started and then
+// block until release is closed.
+func blockingVerifier(started chan<- struct{}, release <-chan struct{}) Verifier {
+	return verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+		started <- struct{}{}
+		<-release
+		return &VerificationResponse{Success: true}, nil
+	})
+}
+
+func TestBoundedVerifierWaitRespectsContext(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	v := NewBoundedVerifier(blockingVerifier(started, release), 1)
+
+	done := make(chan error)
+	go func() { done <- verifyToken(v) }()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := v.Verify(ctx, &VerificationRequest{Response: "token"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Verify() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Verify() of the first call error = %v", err)
+	}
+}
+
+func TestFailFastBoundedVerifier(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	v := NewFailFastBoundedVerifier(blockingVerifier(started, release), 1)
+
+	done := make(chan error)
+	go func() { done <- verifyToken(v) }()
+	<-started
+
+	if err := verifyToken(v); !errors.Is(err, ErrTooManyInFlight) {
+		t.Errorf("Verify() error = %v, want ErrTooManyInFlight", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Verify() of the first call error = %v", err)
+	}
+
+	go func() { <-started }()
+	if err := verifyToken(v); err != nil {
+		t.Errorf("Verify() after the slot was released error = %v", err)
+	}
+}
@@ -0,0 +1,17 @@
+package turnstile
+
+// Chain wraps base in decorators, applied in order so the last one is the
+// outermost and sees each call first:
+//
+//	Chain(client, withRetry, withCache, withMetrics)
+//
+// is equivalent to withMetrics(withCache(withRetry(client))): metrics record
+// every call, cache hits skip retries, and retries wrap only the client.
+func Chain(base Verifier, decorators ...func(Verifier) Verifier) Verifier {
+	v := base
+	for _, decorate := range decorators {
+		v = decorate(v)
+	}
+
+	return v
+}
@@ -0,0 +1,51 @@
+package turnstile
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// recordingDecorator returns a decorator appending "name>" to calls before
+// delegating and "<name" after the delegate returned.
+func recordingDecorator(name string, calls *[]string) func(Verifier) Verifier {
+	return func(next Verifier) Verifier {
+		return verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+			*calls = append(*calls, name+">")
+			defer func() { *calls = append(*calls, "<"+name) }()
+
+			return next.Verify(ctx, req)
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+	base := verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+		calls = append(calls, "base")
+		return &VerificationResponse{Success: true}, nil
+	})
+
+	v := Chain(base,
+		recordingDecorator("retry", &calls),
+		recordingDecorator("cache", &calls),
+		recordingDecorator("metrics", &calls),
+	)
+
+	if err := verifyToken(v); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	want := []string{"metrics>", "cache>", "retry>", "base", "<retry", "<cache", "<metrics"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestChainWithoutDecorators(t *testing.T) {
+	base := NewOfflineVerifier(TestSecretAlwaysPasses)
+
+	if v := Chain(base); v != base {
+		t.Errorf("Chain(base) = %v, want base", v)
+	}
+}
@@ -0,0 +1,22 @@
+package turnstile
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONCodec encodes siteverify requests and decodes its responses.
+type JSONCodec interface {
+	Encode(w io.Writer, v any) error
+	Decode(data []byte, v any) error
+}
+
+type stdJSONCodec struct{}
+
+func (stdJSONCodec) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (stdJSONCodec) Decode(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
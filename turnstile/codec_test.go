@@ -0,0 +1,51 @@
+package turnstile
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+type countingCodec struct {
+	encodes, decodes int
+}
+
+func (c *countingCodec) Encode(w io.Writer, v any) error {
+	c.encodes++
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (c *countingCodec) Decode(data []byte, v any) error {
+	c.decodes++
+	return json.Unmarshal(data, v)
+}
+
+func TestWithJSONCodec(t *testing.T) {
+	var received map[string]any
+	srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("decoding siteverify request: %v", err)
+		}
+		respondJSON(`{"success":true,"hostname":"example.com"}`)(w, r)
+	})
+
+	codec := &countingCodec{}
+	v := NewVerifierClientWithURL("secret", srv.URL, WithJSONCodec(codec))
+
+	resp, err := v.Verify(context.Background(), &VerificationRequest{Response: "token"})
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	if codec.encodes != 1 || codec.decodes != 1 {
+		t.Errorf("codec called %d times to encode and %d to decode, want 1 each", codec.encodes, codec.decodes)
+	}
+	if received["response"] != "token" {
+		t.Errorf("siteverify request = %v, want response token", received)
+	}
+	if resp.Hostname != "example.com" {
+		t.Errorf("Hostname = %q, want %q", resp.Hostname, "example.com")
+	}
+}
@@ -0,0 +1,67 @@
+package turnstile
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+type correlationIDKey struct{}
+
+// ContextWithCorrelationID returns a copy of ctx carrying the given correlation
+// ID, e.g. the ID of the incoming request.
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+func CorrelationIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(correlationIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// CorrelatedError wraps a verification error with the correlation ID of the
+// request that caused it.
+type CorrelatedError struct {
+	CorrelationID string
+	Err           error
+}
+
+func (e *CorrelatedError) Error() string {
+	return fmt.Sprintf("correlation id %s: %v", e.CorrelationID, e.Err)
+}
+
+func (e *CorrelatedError) Unwrap() error {
+	return e.Err
+}
+
+type correlatedVerifier struct {
+	inner  Verifier
+	logger *slog.Logger
+}
+
+// NewCorrelatedVerifier wraps errors returned by inner in a CorrelatedError
+// carrying the correlation ID found in the context, and logs them to logger,
+// or slog.Default() if logger is nil.
+func NewCorrelatedVerifier(inner Verifier, logger *slog.Logger) Verifier {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &correlatedVerifier{inner: inner, logger: logger}
+}
+
+func (v *correlatedVerifier) Verify(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+	resp, err := v.inner.Verify(ctx, req)
+	if err == nil {
+		return resp, nil
+	}
+
+	id, ok := CorrelationIDFromContext(ctx)
+	if !ok {
+		return resp, err
+	}
+
+	v.logger.WarnContext(ctx, "turnstile verification failed", "correlation_id", id, "error", err)
+
+	return resp, &CorrelatedError{CorrelationID: id, Err: err}
+}
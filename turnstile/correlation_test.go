@@ -0,0 +1,49 @@
+package turnstile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestCorrelatedVerifier(t *testing.T) {
+	innerErr := errors.New("boom")
+	var buf bytes.Buffer
+	v := NewCorrelatedVerifier(verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+		return nil, innerErr
+	}), slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	ctx := ContextWithCorrelationID(context.Background(), "req-42")
+	_, err := v.Verify(ctx, &VerificationRequest{Response: "token"})
+
+	var correlated *CorrelatedError
+	if !errors.As(err, &correlated) {
+		t.Fatalf("Verify() error = %v, want *CorrelatedError", err)
+	}
+	if correlated.CorrelationID != "req-42" {
+		t.Errorf("CorrelationID = %q, want %q", correlated.CorrelationID, "req-42")
+	}
+	if !strings.Contains(err.Error(), "req-42") {
+		t.Errorf("Error() = %q, want it to contain the correlation ID", err.Error())
+	}
+	if !errors.Is(err, innerErr) {
+		t.Errorf("Verify() error = %v, want it to wrap %v", err, innerErr)
+	}
+	if !strings.Contains(buf.String(), `"correlation_id":"req-42"`) {
+		t.Errorf("log = %q, want it to contain the correlation ID", buf.String())
+	}
+}
+
+func TestCorrelatedVerifierWithoutID(t *testing.T) {
+	innerErr := errors.New("boom")
+	v := NewCorrelatedVerifier(verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+		return nil, innerErr
+	}), nil)
+
+	if _, err := v.Verify(context.Background(), &VerificationRequest{Response: "token"}); err != innerErr {
+		t.Errorf("Verify() error = %v, want %v", err, innerErr)
+	}
+}
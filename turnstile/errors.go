@@ -0,0 +1,233 @@
+package turnstile
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrorCode is an error code reported by siteverify.
+type ErrorCode string
+
+// Error codes documented by Cloudflare.
+const (
+	CodeMissingInputSecret   ErrorCode = "missing-input-secret"
+	CodeInvalidInputSecret   ErrorCode = "invalid-input-secret"
+	CodeMissingInputResponse ErrorCode = "missing-input-response"
+	CodeInvalidInputResponse ErrorCode = "invalid-input-response"
+	CodeInvalidWidgetID      ErrorCode = "invalid-widget-id"
+	CodeInvalidParsedSecret  ErrorCode = "invalid-parsed-secret"
+	CodeBadRequest           ErrorCode = "bad-request"
+	CodeTimeoutOrDuplicate   ErrorCode = "timeout-or-duplicate"
+	CodeInternalError        ErrorCode = "internal-error"
+)
+
+var (
+	ErrInvalidRequest   = errors.New("invalid request")
+	ErrValidationFailed = errors.New("response validation failed")
+
+	// ErrTokenTooLong is returned for tokens exceeding the maximum token
+	// length. Such tokens come from the client, so KindOf classifies it as a
+	// validation failure; it still matches ErrInvalidRequest as well.
+	ErrTokenTooLong = fmt.Errorf("token too long: %w: %w", ErrValidationFailed, ErrInvalidRequest)
+)
+
+// OutcomeKind classifies the result of a verification, separating normal
+// business outcomes from operational incidents.
+type OutcomeKind int
+
+const (
+	// OutcomeKindSuccess means the token was verified.
+	OutcomeKindSuccess OutcomeKind = iota
+	// OutcomeKindValidationFailure means Cloudflare answered and rejected the
+	// token, a normal business outcome.
+	OutcomeKindValidationFailure
+	// OutcomeKindInvalidRequest means Cloudflare rejected the request itself,
+	// e.g. because of a wrong secret.
+	OutcomeKindInvalidRequest
+	// OutcomeKindServerError means Cloudflare reported an internal error.
+	OutcomeKindServerError
+	// OutcomeKindTransportError means no usable answer was received from
+	// siteverify.
+	OutcomeKindTransportError
+	// OutcomeKindUnknown covers errors that can not be classified.
+	OutcomeKindUnknown
+)
+
+func (k OutcomeKind) String() string {
+	switch k {
+	case OutcomeKindSuccess:
+		return "success"
+	case OutcomeKindValidationFailure:
+		return "validation_failure"
+	case OutcomeKindInvalidRequest:
+		return "invalid_request"
+	case OutcomeKindServerError:
+		return "server_error"
+	case OutcomeKindTransportError:
+		return "transport_error"
+	default:
+		return "unknown"
+	}
+}
+
+// KindOf classifies err, which may wrap a VerificationError. A nil error is a
+// success.
+func KindOf(err error) OutcomeKind {
+	if err == nil {
+		return OutcomeKindSuccess
+	}
+
+	var collapsed *collapsedError
+	if errors.As(err, &collapsed) {
+		return OutcomeKindValidationFailure
+	}
+
+	var verr *VerificationError
+	if errors.As(err, &verr) {
+		return verr.Kind()
+	}
+
+	switch {
+	case errors.Is(err, ErrValidationFailed):
+		return OutcomeKindValidationFailure
+	case errors.Is(err, ErrInvalidRequest):
+		return OutcomeKindInvalidRequest
+	default:
+		return OutcomeKindUnknown
+	}
+}
+
+// VerificationError is returned when a verification fails, either because
+// Cloudflare rejected it, in which case it carries the error codes it
+// reported, or because siteverify could not be reached.
+type VerificationError struct {
+	ErrorCodes []ErrorCode
+	kind       OutcomeKind
+	err        error
+}
+
+func (e *VerificationError) Kind() OutcomeKind {
+	return e.kind
+}
+
+// Error renders the reported error codes sorted and comma-separated, e.g.
+// "... (error codes: invalid-input-response,timeout-or-duplicate)", so log
+// pipelines can match on them reliably.
+func (e *VerificationError) Error() string {
+	if len(e.ErrorCodes) == 0 {
+		return e.err.Error()
+	}
+
+	return fmt.Sprintf("%s (error codes: %s)", e.err, formatErrorCodes(e.ErrorCodes))
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.err
+}
+
+func (e *VerificationError) hasCode(codes ...ErrorCode) bool {
+	for _, code := range codes {
+		if slices.Contains(e.ErrorCodes, code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collapsedError reports a failure as a validation failure, see
+// WithCollapseErrorsToValidationFailed. It is not a *VerificationError, so
+// errors.As still finds the original one along with its error codes.
+type collapsedError struct {
+	err error
+}
+
+func (e *collapsedError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrValidationFailed, e.err)
+}
+
+func (e *collapsedError) Unwrap() []error {
+	return []error{ErrValidationFailed, e.err}
+}
+
+// IsClientCorrectable reports whether err was caused by the token itself, so
+// showing the widget again to the user can resolve it.
+func IsClientCorrectable(err error) bool {
+	var verr *VerificationError
+	if !errors.As(err, &verr) {
+		return false
+	}
+
+	return verr.hasCode(CodeMissingInputResponse, CodeInvalidInputResponse, CodeTimeoutOrDuplicate)
+}
+
+// IsTimeoutOrDuplicate reports whether Cloudflare rejected the token as
+// expired or already redeemed. Frontends can use it to reset the widget and
+// ask for a fresh token instead of showing a generic failure.
+func IsTimeoutOrDuplicate(err error) bool {
+	var verr *VerificationError
+	return errors.As(err, &verr) && verr.hasCode(CodeTimeoutOrDuplicate)
+}
+
+func IsValidationFailed(err error) bool {
+	return errors.Is(err, ErrValidationFailed)
+}
+
+func IsInvalidRequest(err error) bool {
+	return errors.Is(err, ErrInvalidRequest)
+}
+
+// IsTransient reports whether err is likely temporary: siteverify could not be
+// reached or reported an internal error.
+func IsTransient(err error) bool {
+	kind := KindOf(err)
+	return kind == OutcomeKindTransportError || kind == OutcomeKindServerError
+}
+
+func formatErrorCodes(codes []ErrorCode) string {
+	sorted := make([]string, len(codes))
+	for i, code := range codes {
+		sorted[i] = string(code)
+	}
+
+	slices.Sort(sorted)
+	return strings.Join(sorted, ",")
+}
+
+func transportError(err error) error {
+	return &VerificationError{
+		kind: OutcomeKindTransportError,
+		err:  err,
+	}
+}
+
+func mapErrorCodes(codes []ErrorCode) error {
+	kind, err := errorForCodes(codes)
+	return &VerificationError{
+		ErrorCodes: codes,
+		kind:       kind,
+		err:        err,
+	}
+}
+
+func errorForCodes(codes []ErrorCode) (OutcomeKind, error) {
+	switch {
+	case slices.Contains(codes, CodeInternalError):
+		return OutcomeKindServerError, errors.New("turnstile server error")
+
+	case slices.Contains(codes, CodeInvalidInputResponse) || slices.Contains(codes, CodeTimeoutOrDuplicate):
+		return OutcomeKindValidationFailure,
+			fmt.Errorf("invalid, duplicate or expired response: %w", ErrValidationFailed)
+
+	case slices.Contains(codes, CodeBadRequest) || slices.Contains(codes, CodeMissingInputSecret) ||
+		slices.Contains(codes, CodeInvalidInputSecret) || slices.Contains(codes, CodeInvalidParsedSecret) ||
+		slices.Contains(codes, CodeMissingInputSecret) || slices.Contains(codes, CodeInvalidWidgetID) ||
+		slices.Contains(codes, CodeMissingInputResponse):
+		return OutcomeKindInvalidRequest, fmt.Errorf("validation error(s) on turnstile: %w", ErrInvalidRequest)
+
+	default:
+		return OutcomeKindUnknown, errors.New("unhandled turnstile error")
+	}
+}
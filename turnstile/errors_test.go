@@ -0,0 +1,209 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestIsClientCorrectable(t *testing.T) {
+	tests := []struct {
+		code ErrorCode
+		want bool
+	}{
+		{CodeMissingInputResponse, true},
+		{CodeInvalidInputResponse, true},
+		{CodeTimeoutOrDuplicate, true},
+		{CodeMissingInputSecret, false},
+		{CodeInvalidInputSecret, false},
+		{CodeInvalidWidgetID, false},
+		{CodeInvalidParsedSecret, false},
+		{CodeBadRequest, false},
+		{CodeInternalError, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.code), func(t *testing.T) {
+			if got := IsClientCorrectable(mapErrorCodes([]ErrorCode{tt.code})); got != tt.want {
+				t.Errorf("IsClientCorrectable(%s) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsClientCorrectableOtherErrors(t *testing.T) {
+	for _, err := range []error{nil, errors.New("boom"), transportError(errors.New("unreachable"))} {
+		if IsClientCorrectable(err) {
+			t.Errorf("IsClientCorrectable(%v) = true, want false", err)
+		}
+	}
+}
+
+func TestKindOfVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    OutcomeKind
+	}{
+		{name: "success", handler: respondJSON(`{"success":true}`), want: OutcomeKindSuccess},
+		{
+			name:    "validation failure",
+			handler: respondJSON(`{"success":false,"error-codes":["invalid-input-response"]}`),
+			want:    OutcomeKindValidationFailure,
+		},
+		{
+			name:    "invalid request",
+			handler: respondJSON(`{"success":false,"error-codes":["invalid-input-secret"]}`),
+			want:    OutcomeKindInvalidRequest,
+		},
+		{
+			name:    "server error",
+			handler: respondJSON(`{"success":false,"error-codes":["internal-error"]}`),
+			want:    OutcomeKindServerError,
+		},
+		{
+			name: "transport error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			want: OutcomeKindTransportError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVerifierClientWithHandler("secret", tt.handler)
+
+			_, err := v.Verify(context.Background(), &VerificationRequest{Response: "token"})
+			if got := KindOf(err); got != tt.want {
+				t.Errorf("KindOf(%v) = %v, want %v", err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	tests := []struct {
+		name             string
+		err              error
+		validationFailed bool
+		invalidRequest   bool
+		transient        bool
+	}{
+		{name: "nil"},
+		{name: "unclassified", err: errors.New("boom")},
+		{name: "invalid input response", err: mapErrorCodes([]ErrorCode{CodeInvalidInputResponse}), validationFailed: true},
+		{name: "timeout or duplicate", err: mapErrorCodes([]ErrorCode{CodeTimeoutOrDuplicate}), validationFailed: true},
+		{name: "invalid secret", err: mapErrorCodes([]ErrorCode{CodeInvalidInputSecret}), invalidRequest: true},
+		{name: "internal error", err: mapErrorCodes([]ErrorCode{CodeInternalError}), transient: true},
+		{name: "transport error", err: transportError(errors.New("connection refused")), transient: true},
+		{name: "wrapped", err: fmt.Errorf("verifying: %w", mapErrorCodes([]ErrorCode{CodeInvalidInputResponse})), validationFailed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsValidationFailed(tt.err); got != tt.validationFailed {
+				t.Errorf("IsValidationFailed(%v) = %v, want %v", tt.err, got, tt.validationFailed)
+			}
+			if got := IsInvalidRequest(tt.err); got != tt.invalidRequest {
+				t.Errorf("IsInvalidRequest(%v) = %v, want %v", tt.err, got, tt.invalidRequest)
+			}
+			if got := IsTransient(tt.err); got != tt.transient {
+				t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.transient)
+			}
+		})
+	}
+}
+
+func TestVerificationErrorCodesRendering(t *testing.T) {
+	tests := []struct {
+		codes []ErrorCode
+		want  string
+	}{
+		{codes: []ErrorCode{CodeTimeoutOrDuplicate}, want: "(error codes: timeout-or-duplicate)"},
+		{
+			codes: []ErrorCode{CodeTimeoutOrDuplicate, CodeInvalidInputResponse},
+			want:  "(error codes: invalid-input-response,timeout-or-duplicate)",
+		},
+		{
+			codes: []ErrorCode{CodeInvalidInputResponse, CodeTimeoutOrDuplicate},
+			want:  "(error codes: invalid-input-response,timeout-or-duplicate)",
+		},
+	}
+
+	for _, tt := range tests {
+		err := mapErrorCodes(tt.codes)
+		if !strings.HasSuffix(err.Error(), " "+tt.want) {
+			t.Errorf("Error() = %q, want suffix %q", err.Error(), tt.want)
+		}
+	}
+
+	codes := []ErrorCode{CodeTimeoutOrDuplicate, CodeInvalidInputResponse}
+	_ = mapErrorCodes(codes).Error()
+	if codes[0] != CodeTimeoutOrDuplicate {
+		t.Errorf("Error() reordered the error codes to %v", codes)
+	}
+
+	if err := transportError(errors.New("boom")); err.Error() != "boom" {
+		t.Errorf("Error() = %q without codes, want %q", err.Error(), "boom")
+	}
+}
+
+func TestErrorCodeValues(t *testing.T) {
+	for code, want := range map[ErrorCode]string{
+		CodeMissingInputSecret:   "missing-input-secret",
+		CodeInvalidInputSecret:   "invalid-input-secret",
+		CodeMissingInputResponse: "missing-input-response",
+		CodeInvalidInputResponse: "invalid-input-response",
+		CodeInvalidWidgetID:      "invalid-widget-id",
+		CodeInvalidParsedSecret:  "invalid-parsed-secret",
+		CodeBadRequest:           "bad-request",
+		CodeTimeoutOrDuplicate:   "timeout-or-duplicate",
+		CodeInternalError:        "internal-error",
+	} {
+		if string(code) != want {
+			t.Errorf("error code %q, want %q", code, want)
+		}
+	}
+}
+
+func TestVerificationErrorCarriesCodes(t *testing.T) {
+	srv := newSiteverifyServer(t, respondJSON(`{"success":false,"error-codes":["timeout-or-duplicate"]}`))
+
+	var verr *VerificationError
+	if err := verifyToken(NewVerifierClientWithURL("secret", srv.URL)); !errors.As(err, &verr) {
+		t.Fatalf("Verify() error = %v, want *VerificationError", err)
+	}
+
+	if len(verr.ErrorCodes) != 1 || verr.ErrorCodes[0] != CodeTimeoutOrDuplicate {
+		t.Errorf("ErrorCodes = %v, want [%s]", verr.ErrorCodes, CodeTimeoutOrDuplicate)
+	}
+}
+
+func TestIsTimeoutOrDuplicate(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "timeout or duplicate", err: mapErrorCodes([]ErrorCode{CodeTimeoutOrDuplicate}), want: true},
+		{name: "among other codes", err: mapErrorCodes([]ErrorCode{CodeInvalidInputResponse, CodeTimeoutOrDuplicate}), want: true},
+		{name: "wrapped", err: fmt.Errorf("verifying: %w", mapErrorCodes([]ErrorCode{CodeTimeoutOrDuplicate})), want: true},
+		{name: "invalid input response", err: mapErrorCodes([]ErrorCode{CodeInvalidInputResponse})},
+		{name: "internal error", err: mapErrorCodes([]ErrorCode{CodeInternalError})},
+		{name: "transport error", err: transportError(errors.New("unreachable"))},
+		{name: "unclassified", err: errors.New("timeout-or-duplicate")},
+		{name: "nil"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTimeoutOrDuplicate(tt.err); got != tt.want {
+				t.Errorf("IsTimeoutOrDuplicate(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
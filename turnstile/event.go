@@ -0,0 +1,30 @@
+package turnstile
+
+import "time"
+
+// VerifyEvent describes a single verification. It never contains the secret
+// or the token.
+type VerifyEvent struct {
+	Outcome    OutcomeKind
+	Hostname   string
+	Action     string
+	ErrorCodes []ErrorCode
+	Latency    time.Duration
+	Err        error
+}
+
+func newVerifyEvent(resp *VerificationResponse, err error, latency time.Duration) VerifyEvent {
+	e := VerifyEvent{
+		Outcome: KindOf(err),
+		Latency: latency,
+		Err:     err,
+	}
+
+	if resp != nil {
+		e.Hostname = resp.Hostname
+		e.Action = resp.Action
+		e.ErrorCodes = resp.ErrorCodes
+	}
+
+	return e
+}
@@ -0,0 +1,46 @@
+package turnstile
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestWithEventSink(t *testing.T) {
+	responses := []string{
+		`{"success":true,"hostname":"example.com","action":"login"}`,
+		`{"success":false,"error-codes":["timeout-or-duplicate"]}`,
+	}
+
+	var calls int
+	srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		respondJSON(responses[calls])(w, r)
+		calls++
+	})
+
+	var events []VerifyEvent
+	v := NewVerifierClientWithURL("secret", srv.URL, WithEventSink(func(ctx context.Context, e VerifyEvent) {
+		events = append(events, e)
+	}))
+
+	for range responses {
+		verifyToken(v)
+	}
+
+	if len(events) != len(responses) {
+		t.Fatalf("sink received %d events, want %d", len(events), len(responses))
+	}
+
+	if e := events[0]; e.Outcome != OutcomeKindSuccess || e.Hostname != "example.com" || e.Action != "login" || e.Err != nil {
+		t.Errorf("events[0] = %+v, want a successful login on example.com", e)
+	}
+	if e := events[1]; e.Outcome != OutcomeKindValidationFailure || !slices.Equal(e.ErrorCodes, []ErrorCode{CodeTimeoutOrDuplicate}) || e.Err == nil {
+		t.Errorf("events[1] = %+v, want a validation failure with timeout-or-duplicate", e)
+	}
+	for i, e := range events {
+		if e.Latency <= 0 {
+			t.Errorf("events[%d].Latency = %v, want a positive latency", i, e.Latency)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package turnstile
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrActionMismatch     = fmt.Errorf("action mismatch: %w", ErrValidationFailed)
+	ErrHostnameNotAllowed = fmt.Errorf("hostname not allowed: %w", ErrValidationFailed)
+	ErrChallengeExpired   = fmt.Errorf("challenge expired: %w", ErrValidationFailed)
+	// ErrInconsistentResponse is reported in strict mode for responses
+	// claiming success while also carrying error codes.
+	ErrInconsistentResponse = fmt.Errorf("inconsistent response: %w", ErrValidationFailed)
+)
+
+// ExpectationError lists every expectation a successful response failed to
+// meet. errors.Is matches each of the underlying errors.
+type ExpectationError struct {
+	Errs []error
+}
+
+func (e *ExpectationError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+
+	return "turnstile response did not meet expectations: " + strings.Join(msgs, "; ")
+}
+
+func (e *ExpectationError) Unwrap() []error {
+	return e.Errs
+}
+
+// ResponseChecker is implemented by the Verifier returned by the constructors,
+// applying its expectations, such as WithExpectedAction or
+// WithAllowedHostnames, to a response verified elsewhere, e.g. by a stacked
+// middleware that already redeemed the token.
+type ResponseChecker interface {
+	CheckResponse(ctx context.Context, resp *VerificationResponse) error
+}
+
+func (t *verifierClient) CheckResponse(ctx context.Context, resp *VerificationResponse) error {
+	return t.checkExpectations(ctx, resp)
+}
+
+func (t *verifierClient) checkExpectations(ctx context.Context, resp *VerificationResponse) error {
+	var errs []error
+
+	if t.strictMode && len(resp.ErrorCodes) > 0 {
+		errs = append(errs, fmt.Errorf("success with error codes %s: %w",
+			formatErrorCodes(resp.ErrorCodes), ErrInconsistentResponse))
+	}
+
+	if len(t.allowedActions) > 0 && !slices.Contains(t.allowedActions, resp.Action) {
+		errs = append(errs, fmt.Errorf("got action %q, want one of %q: %w", resp.Action, t.allowedActions, ErrActionMismatch))
+	}
+
+	if t.hostnamePatternErr != nil {
+		errs = append(errs, fmt.Errorf("%w: %w", t.hostnamePatternErr, ErrHostnameNotAllowed))
+	} else if resp.Hostname == "" && (t.strictMode || len(t.allowedHostnames) > 0) {
+		errs = append(errs, fmt.Errorf("missing hostname: %w", ErrHostnameNotAllowed))
+	} else if len(t.allowedHostnames) > 0 && !hostnameAllowed(t.allowedHostnames, resp.Hostname) {
+		errs = append(errs, fmt.Errorf("got hostname %q: %w", resp.Hostname, ErrHostnameNotAllowed))
+	} else if t.hostnameValidator != nil {
+		if err := t.hostnameValidator(ctx, resp.Hostname); err != nil {
+			errs = append(errs, fmt.Errorf("hostname %q rejected: %w: %w", resp.Hostname, ErrHostnameNotAllowed, err))
+		}
+	}
+
+	if t.maxChallengeAge > 0 {
+		if age := time.Since(resp.ChallengeTs); age > t.maxChallengeAge {
+			errs = append(errs, fmt.Errorf("challenge solved %s ago: %w", age.Round(time.Second), ErrChallengeExpired))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &ExpectationError{Errs: errs}
+}
+
+// hostnameAllowed reports whether hostname matches one of patterns, ignoring
+// case. A pattern starting with "." or "*." matches any subdomain of the rest
+// of the pattern, but not the domain itself; other patterns match exactly.
+func hostnameAllowed(patterns []string, hostname string) bool {
+	hostname = strings.ToLower(hostname)
+	for _, pattern := range patterns {
+		if suffix, ok := hostnameSuffix(pattern); ok {
+			if strings.HasSuffix(hostname, suffix) && len(hostname) > len(suffix) {
+				return true
+			}
+		} else if strings.EqualFold(pattern, hostname) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hostnameSuffix returns the lowercased suffix, including the leading dot, of
+// a subdomain pattern.
+func hostnameSuffix(pattern string) (string, bool) {
+	if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+		return "." + strings.ToLower(domain), true
+	}
+
+	if strings.HasPrefix(pattern, ".") {
+		return strings.ToLower(pattern), true
+	}
+
+	return "", false
+}
+
+// validateHostnamePattern rejects subdomain patterns broader than a
+// registrable domain, such as "." or ".com", which would allow hostnames the
+// site does not control.
+func validateHostnamePattern(pattern string) error {
+	suffix, ok := hostnameSuffix(pattern)
+	if !ok {
+		return nil
+	}
+
+	labels := strings.Split(suffix[1:], ".")
+	if len(labels) < 2 || slices.Contains(labels, "") {
+		return fmt.Errorf("hostname pattern %q is too broad", pattern)
+	}
+
+	return nil
+}
@@ -0,0 +1,260 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpectationErrorAllFailures(t *testing.T) {
+	challengeTs := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	srv := newSiteverifyServer(t, respondJSON(`{"success":true,"action":"login","hostname":"evil.example","challenge_ts":"`+challengeTs+`"}`))
+
+	v := NewVerifierClientWithURL("secret", srv.URL,
+		WithExpectedAction("signup"),
+		WithAllowedHostnames("example.com"),
+		WithMaxChallengeAge(time.Minute),
+	)
+
+	err := verifyToken(v)
+
+	var expErr *ExpectationError
+	if !errors.As(err, &expErr) {
+		t.Fatalf("Verify() error = %v, want *ExpectationError", err)
+	}
+	if len(expErr.Errs) != 3 {
+		t.Errorf("ExpectationError.Errs = %v, want 3 errors", expErr.Errs)
+	}
+
+	for _, target := range []error{ErrActionMismatch, ErrHostnameNotAllowed, ErrChallengeExpired, ErrValidationFailed} {
+		if !errors.Is(err, target) {
+			t.Errorf("errors.Is(%v, %v) = false, want true", err, target)
+		}
+	}
+}
+
+func TestExpectationErrorSingleFailure(t *testing.T) {
+	srv := newSiteverifyServer(t, respondJSON(`{"success":true,"action":"login","hostname":"example.com"}`))
+
+	err := verifyToken(NewVerifierClientWithURL("secret", srv.URL,
+		WithExpectedAction("signup"),
+		WithAllowedHostnames("example.com"),
+	))
+
+	if !errors.Is(err, ErrActionMismatch) {
+		t.Errorf("Verify() error = %v, want ErrActionMismatch", err)
+	}
+	if errors.Is(err, ErrHostnameNotAllowed) || errors.Is(err, ErrChallengeExpired) {
+		t.Errorf("Verify() error = %v matches expectations that were met", err)
+	}
+}
+
+func TestWithAllowedActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []string
+		action  string
+		wantErr bool
+	}{
+		{name: "in set", actions: []string{"login", "signup"}, action: "signup"},
+		{name: "not in set", actions: []string{"login", "signup"}, action: "checkout", wantErr: true},
+		{name: "missing action", actions: []string{"login"}, wantErr: true},
+		{name: "empty set", action: "checkout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSiteverifyServer(t, respondJSON(`{"success":true,"hostname":"example.com","action":"`+tt.action+`"}`))
+
+			err := verifyToken(NewVerifierClientWithURL("secret", srv.URL, WithAllowedActions(tt.actions...)))
+			if got := errors.Is(err, ErrActionMismatch); got != tt.wantErr {
+				t.Errorf("Verify() error = %v, want ErrActionMismatch %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestWithStrictMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		opts    []Option
+		want    error
+		wantErr bool
+	}{
+		{name: "passes", body: `{"success":true,"hostname":"example.com"}`},
+		{
+			name:    "not successful",
+			body:    `{"success":false,"hostname":"example.com","error-codes":["invalid-input-response"]}`,
+			want:    ErrValidationFailed,
+			wantErr: true,
+		},
+		{
+			name:    "error codes",
+			body:    `{"success":true,"hostname":"example.com","error-codes":["internal-error"]}`,
+			want:    ErrInconsistentResponse,
+			wantErr: true,
+		},
+		{name: "missing hostname", body: `{"success":true}`, want: ErrHostnameNotAllowed, wantErr: true},
+		{
+			name:    "hostname not allowed",
+			body:    `{"success":true,"hostname":"evil.example"}`,
+			opts:    []Option{WithAllowedHostnames("example.com")},
+			want:    ErrHostnameNotAllowed,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSiteverifyServer(t, respondJSON(tt.body))
+
+			err := verifyToken(NewVerifierClientWithURL("secret", srv.URL, append(tt.opts, WithStrictMode())...))
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("Verify() error = %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+
+			// An unsuccessful response is reported alone, before any expectation.
+			var expErr *ExpectationError
+			if isExpErr := errors.As(err, &expErr); isExpErr != (tt.want != ErrValidationFailed) {
+				t.Errorf("Verify() error = %v, want ExpectationError %v", err, !isExpErr)
+			} else if isExpErr && len(expErr.Errs) != 1 {
+				t.Errorf("ExpectationError.Errs = %v, want only the failing check", expErr.Errs)
+			}
+		})
+	}
+}
+
+func TestWithoutStrictModeIgnoresCodesAndHostname(t *testing.T) {
+	srv := newSiteverifyServer(t, respondJSON(`{"success":true,"error-codes":["internal-error"]}`))
+
+	if err := verifyToken(NewVerifierClientWithURL("secret", srv.URL)); err != nil {
+		t.Errorf("Verify() error = %v, want nil", err)
+	}
+}
+
+func TestWithAllowedHostnames(t *testing.T) {
+	tests := []struct {
+		name      string
+		hostnames []string
+		hostname  string
+		wantErr   bool
+	}{
+		{name: "exact", hostnames: []string{"example.com"}, hostname: "example.com"},
+		{name: "exact ignoring case", hostnames: []string{"Example.com"}, hostname: "EXAMPLE.com"},
+		{name: "exact rejects subdomain", hostnames: []string{"example.com"}, hostname: "www.example.com", wantErr: true},
+		{name: "dot pattern subdomain", hostnames: []string{".example.com"}, hostname: "www.example.com"},
+		{name: "dot pattern nested subdomain", hostnames: []string{".example.com"}, hostname: "a.b.example.com"},
+		{name: "star pattern subdomain", hostnames: []string{"*.example.com"}, hostname: "WWW.example.com"},
+		{name: "pattern rejects domain itself", hostnames: []string{".example.com"}, hostname: "example.com", wantErr: true},
+		{name: "pattern rejects lookalike", hostnames: []string{".example.com"}, hostname: "badexample.com", wantErr: true},
+		{name: "pattern and domain", hostnames: []string{"example.com", "*.example.com"}, hostname: "example.com"},
+		{name: "missing hostname", hostnames: []string{".example.com"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSiteverifyServer(t, respondJSON(`{"success":true,"hostname":"`+tt.hostname+`"}`))
+
+			err := verifyToken(NewVerifierClientWithURL("secret", srv.URL, WithAllowedHostnames(tt.hostnames...)))
+			if got := errors.Is(err, ErrHostnameNotAllowed); got != tt.wantErr {
+				t.Errorf("Verify() error = %v, want ErrHostnameNotAllowed %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestWithAllowedHostnamesTooBroadRejects(t *testing.T) {
+	srv := newSiteverifyServer(t, respondJSON(`{"success":true,"hostname":"www.example.com"}`))
+
+	for _, pattern := range []string{".", "*.", ".com", "*.com", "..example.com", ".example..com"} {
+		t.Run(pattern, func(t *testing.T) {
+			// The valid pattern would match, the broad one rejects regardless.
+			v := NewVerifierClientWithURL("secret", srv.URL, WithAllowedHostnames(".example.com", pattern))
+
+			err := verifyToken(v)
+			if !errors.Is(err, ErrHostnameNotAllowed) {
+				t.Errorf("Verify() error = %v, want ErrHostnameNotAllowed", err)
+			}
+			if err == nil || !strings.Contains(err.Error(), "too broad") {
+				t.Errorf("Verify() error = %v, want too broad pattern", err)
+			}
+		})
+	}
+}
+
+func TestWithHostnameValidator(t *testing.T) {
+	errUnknownTenant := errors.New("unknown tenant")
+	tenants := map[string]bool{"tenant.example.com": true}
+
+	validate := func(ctx context.Context, hostname string) error {
+		if !tenants[hostname] {
+			return errUnknownTenant
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		success  bool
+		hostname string
+		wantErr  bool
+		rejected bool
+		calls    int
+	}{
+		{name: "allowed", success: true, hostname: "tenant.example.com", calls: 1},
+		{name: "rejected", success: true, hostname: "other.example.com", wantErr: true, rejected: true, calls: 1},
+		{name: "failed verification", hostname: "tenant.example.com", wantErr: true},
+		{name: "static allowlist first", opts: []Option{WithAllowedHostnames("example.com")}, success: true, hostname: "tenant.example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"success":%t,"hostname":%q}`, tt.success, tt.hostname)
+			if !tt.success {
+				body = fmt.Sprintf(`{"success":false,"hostname":%q,"error-codes":["invalid-input-response"]}`, tt.hostname)
+			}
+			srv := newSiteverifyServer(t, respondJSON(body))
+
+			var calls int
+			opts := append([]Option{WithHostnameValidator(func(ctx context.Context, hostname string) error {
+				calls++
+				return validate(ctx, hostname)
+			})}, tt.opts...)
+
+			err := verifyToken(NewVerifierClientWithURL("secret", srv.URL, opts...))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.calls {
+				t.Errorf("validator calls = %d, want %d", calls, tt.calls)
+			}
+
+			if tt.rejected {
+				if !errors.Is(err, ErrHostnameNotAllowed) || !errors.Is(err, errUnknownTenant) {
+					t.Errorf("Verify() error = %v, want it to wrap ErrHostnameNotAllowed and the validator error", err)
+				}
+				if !IsValidationFailed(err) {
+					t.Errorf("IsValidationFailed(%v) = false, want true", err)
+				}
+			}
+		})
+	}
+}
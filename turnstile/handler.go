@@ -0,0 +1,70 @@
+package turnstile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// NewVerifierClientWithHandler sends siteverify requests straight to h in
+// process, without a listener or network access, for end-to-end tests against
+// a stubbed siteverify. Options replacing the HTTP client or tuning its
+// transport have no effect.
+func NewVerifierClientWithHandler(secret string, h http.Handler, opts ...Option) Verifier {
+	opts = append(opts[:len(opts):len(opts)], WithHTTPClient(&http.Client{Transport: handlerTransport{handler: h}}))
+	return NewVerifierClient(secret, opts...)
+}
+
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+
+	w := &bufferedResponseWriter{header: make(http.Header)}
+	t.handler.ServeHTTP(w, req)
+
+	return w.response(req), nil
+}
+
+// bufferedResponseWriter records the response of an in-process handler.
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferedResponseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+func (w *bufferedResponseWriter) response(req *http.Request) *http.Response {
+	w.WriteHeader(http.StatusOK)
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", w.status, http.StatusText(w.status)),
+		StatusCode:    w.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.header,
+		Body:          io.NopCloser(bytes.NewReader(w.body.Bytes())),
+		ContentLength: int64(w.body.Len()),
+		Request:       req,
+	}
+}
@@ -0,0 +1,45 @@
+package turnstile
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestNewVerifierClientWithHandler(t *testing.T) {
+	var body map[string]any
+	v := NewVerifierClientWithHandler("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want %s", r.Method, http.MethodPost)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Decode() error = %v", err)
+		}
+
+		respondJSON(`{"success":true,"hostname":"example.com","action":"login","cdata":"session"}`)(w, r)
+	}))
+
+	resp, err := v.Verify(context.Background(), &VerificationRequest{Response: "token", RemoteIP: "203.0.113.1"})
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	for field, want := range map[string]string{"secret": "secret", "response": "token", "remoteip": "203.0.113.1"} {
+		if body[field] != want {
+			t.Errorf("body[%q] = %v, want %q", field, body[field], want)
+		}
+	}
+
+	if resp.Hostname != "example.com" || resp.Action != "login" || resp.Cdata != "session" {
+		t.Errorf("Verify() = %+v, want canned response", resp)
+	}
+}
+
+func TestNewVerifierClientWithHandlerFailure(t *testing.T) {
+	v := NewVerifierClientWithHandler("secret", respondJSON(`{"success":false,"error-codes":["invalid-input-response"]}`))
+
+	if err := verifyToken(v); !IsValidationFailed(err) {
+		t.Errorf("IsValidationFailed(%v) = false, want true", err)
+	}
+}
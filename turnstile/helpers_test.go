@@ -0,0 +1,54 @@
+package turnstile
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+type verifierFunc func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error)
+
+func (f verifierFunc) Verify(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+	return f(ctx, req)
+}
+
+// verifyToken verifies a dummy token with v and returns the error.
+func verifyToken(v Verifier) error {
+	_, err := v.Verify(context.Background(), &VerificationRequest{Response: "token"})
+	return err
+}
+
+// newSiteverifyServer starts a stub siteverify server closed at the end of
+// the test.
+func newSiteverifyServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// respondJSON returns a handler answering every request with body.
+func respondJSON(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}
+}
+
+// redirectTransport sends every request to target instead of its own host.
+type redirectTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+
+	return t.next.RoundTrip(req)
+}
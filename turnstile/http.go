@@ -0,0 +1,67 @@
+package turnstile
+
+import (
+	"context"
+	"net/http"
+)
+
+const tokenFieldName = "cf-turnstile-response"
+
+type responseContextKey struct{}
+
+// ContextWithResponse returns a copy of ctx carrying resp.
+func ContextWithResponse(ctx context.Context, resp *VerificationResponse) context.Context {
+	return context.WithValue(ctx, responseContextKey{}, resp)
+}
+
+func ResponseFromContext(ctx context.Context) (*VerificationResponse, bool) {
+	resp, ok := ctx.Value(responseContextKey{}).(*VerificationResponse)
+	return resp, ok
+}
+
+// ResponseFromRequest returns the response stored by the middleware returned
+// from NewHTTPMiddleware, so handlers can inspect it without verifying again.
+func ResponseFromRequest(r *http.Request) (*VerificationResponse, bool) {
+	return ResponseFromContext(r.Context())
+}
+
+// NewHTTPMiddleware returns a net/http middleware verifying the token sent in
+// the cf-turnstile-response header or form field. Verified requests reach next
+// with the VerificationResponse stored in their context.
+func NewHTTPMiddleware(v Verifier) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get(tokenFieldName)
+			if token == "" {
+				token = r.PostFormValue(tokenFieldName)
+			}
+
+			if token == "" {
+				http.Error(w, "missing turnstile response", http.StatusBadRequest)
+				return
+			}
+
+			resp, err := v.Verify(r.Context(), &VerificationRequest{
+				Response: token,
+				RemoteIP: RemoteIPFromRequest(r, false),
+			})
+			if err != nil {
+				http.Error(w, http.StatusText(httpStatusOf(err)), httpStatusOf(err))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(ContextWithResponse(r.Context(), resp)))
+		})
+	}
+}
+
+func httpStatusOf(err error) int {
+	switch KindOf(err) {
+	case OutcomeKindValidationFailure:
+		return http.StatusBadRequest
+	case OutcomeKindTransportError, OutcomeKindServerError:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,63 @@
+package turnstile
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestNewHTTPMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier Verifier
+		header   string
+		form     string
+		status   int
+	}{
+		{name: "header", verifier: NewOfflineVerifier(TestSecretAlwaysPasses), header: DummyToken, status: http.StatusOK},
+		{name: "form field", verifier: NewOfflineVerifier(TestSecretAlwaysPasses), form: DummyToken, status: http.StatusOK},
+		{name: "missing token", verifier: NewOfflineVerifier(TestSecretAlwaysPasses), status: http.StatusBadRequest},
+		{name: "failed", verifier: NewOfflineVerifier(TestSecretAlwaysFails), header: DummyToken, status: http.StatusBadRequest},
+		{name: "invalid secret", verifier: NewOfflineVerifier("secret"), header: DummyToken, status: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *VerificationResponse
+			handler := NewHTTPMiddleware(tt.verifier)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				resp, ok := ResponseFromRequest(r)
+				if !ok {
+					t.Error("ResponseFromRequest() ok = false, want true")
+				}
+				got = resp
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{tokenFieldName: {tt.form}}.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.header != "" {
+				req.Header.Set(tokenFieldName, tt.header)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusOK && (got == nil || !got.Success) {
+				t.Errorf("handler read response %+v, want a successful one", got)
+			}
+			if tt.status != http.StatusOK && got != nil {
+				t.Error("handler called for a rejected request")
+			}
+		})
+	}
+}
+
+func TestResponseFromRequestWithoutMiddleware(t *testing.T) {
+	if resp, ok := ResponseFromRequest(httptest.NewRequest(http.MethodGet, "/", nil)); ok || resp != nil {
+		t.Errorf("ResponseFromRequest() = %v, %v, want nil, false", resp, ok)
+	}
+}
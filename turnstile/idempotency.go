@@ -0,0 +1,18 @@
+package turnstile
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// deriveIdempotencyKey formats the first 16 bytes of the token's SHA-256 hash
+// as a UUID, the format Cloudflare expects for idempotency keys. The token
+// itself can not be recovered from the key.
+func deriveIdempotencyKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	b := sum[:16]
+	b[6] = (b[6] & 0x0f) | 0x80 // version 8, custom
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
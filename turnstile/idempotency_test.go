@@ -0,0 +1,61 @@
+package turnstile
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-8[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestDeriveIdempotencyKey(t *testing.T) {
+	key := deriveIdempotencyKey("token")
+
+	if !uuidPattern.MatchString(key) {
+		t.Errorf("deriveIdempotencyKey() = %q, want a version 8 UUID", key)
+	}
+	if got := deriveIdempotencyKey("token"); got != key {
+		t.Errorf("deriveIdempotencyKey() = %q for the same token, want %q", got, key)
+	}
+	if got := deriveIdempotencyKey("other"); got == key {
+		t.Errorf("deriveIdempotencyKey() = %q for a different token, want a different key", got)
+	}
+}
+
+func TestWithTokenDerivedIdempotencyKey(t *testing.T) {
+	var keys []string
+	srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding siteverify request: %v", err)
+		}
+		if strings.Contains(body["idempotency_key"], "token") {
+			t.Errorf("idempotency_key %q contains the token", body["idempotency_key"])
+		}
+
+		keys = append(keys, body["idempotency_key"])
+		respondJSON(`{"success":true}`)(w, r)
+	})
+
+	v := NewVerifierClientWithURL("secret", srv.URL, WithTokenDerivedIdempotencyKey())
+
+	for _, req := range []*VerificationRequest{
+		{Response: "token"},
+		{Response: "token"},
+		{Response: "token", IdempotencyKey: "explicit"},
+	} {
+		if _, err := v.Verify(context.Background(), req); err != nil {
+			t.Fatalf("Verify() error = %v", err)
+		}
+	}
+
+	if keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("derived keys = %q, %q, want identical non-empty keys", keys[0], keys[1])
+	}
+	if keys[2] != "explicit" {
+		t.Errorf("idempotency_key = %q, want the explicit key", keys[2])
+	}
+}
@@ -0,0 +1,87 @@
+package turnstile
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrDuplicateIdempotencyKey = fmt.Errorf("duplicate idempotency key: %w", ErrValidationFailed)
+
+type idempotencyGuardVerifier struct {
+	inner  Verifier
+	window time.Duration
+
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+// NewIdempotencyGuardVerifier rejects a request with
+// ErrDuplicateIdempotencyKey when its idempotency key was already seen within
+// window, guarding against replays even when the token differs. Requests
+// without an idempotency key are passed through. Keys of calls failing without
+// a verdict from Cloudflare, see IsTransient, are released so the call can be
+// retried. Expired keys are pruned as new requests arrive.
+func NewIdempotencyGuardVerifier(inner Verifier, window time.Duration) Verifier {
+	return &idempotencyGuardVerifier{
+		inner:     inner,
+		window:    window,
+		seen:      make(map[string]time.Time),
+		lastPrune: time.Now(),
+	}
+}
+
+func (v *idempotencyGuardVerifier) Verify(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+	if req.IdempotencyKey == "" {
+		return v.inner.Verify(ctx, req)
+	}
+
+	now := time.Now()
+	if !v.claim(req.IdempotencyKey, now) {
+		return nil, ErrDuplicateIdempotencyKey
+	}
+
+	resp, err := v.inner.Verify(ctx, req)
+	if IsTransient(err) {
+		// No verdict was reached, so the key may be retried.
+		v.unclaim(req.IdempotencyKey, now)
+	}
+
+	return resp, err
+}
+
+// claim records key as seen at now, reporting false if it was already seen
+// within the window.
+func (v *idempotencyGuardVerifier) claim(key string, now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if now.Sub(v.lastPrune) >= v.window {
+		for k, seenAt := range v.seen {
+			if now.Sub(seenAt) >= v.window {
+				delete(v.seen, k)
+			}
+		}
+
+		v.lastPrune = now
+	}
+
+	if seenAt, ok := v.seen[key]; ok && now.Sub(seenAt) < v.window {
+		return false
+	}
+
+	v.seen[key] = now
+	return true
+}
+
+// unclaim forgets key if it is still recorded as seen at claimedAt.
+func (v *idempotencyGuardVerifier) unclaim(key string, claimedAt time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if seenAt, ok := v.seen[key]; ok && seenAt.Equal(claimedAt) {
+		delete(v.seen, key)
+	}
+}
@@ -0,0 +1,103 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyGuardVerifier(t *testing.T) {
+	calls := 0
+	v := NewIdempotencyGuardVerifier(verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+		calls++
+		return &VerificationResponse{Success: true}, nil
+	}), 50*time.Millisecond)
+
+	verify := func(key string) error {
+		_, err := v.Verify(context.Background(), &VerificationRequest{Response: "token", IdempotencyKey: key})
+		return err
+	}
+
+	if err := verify("key"); err != nil {
+		t.Fatalf("first Verify() error = %v", err)
+	}
+
+	err := verify("key")
+	if !errors.Is(err, ErrDuplicateIdempotencyKey) {
+		t.Errorf("repeated Verify() error = %v, want %v", err, ErrDuplicateIdempotencyKey)
+	}
+	if !IsValidationFailed(err) {
+		t.Errorf("IsValidationFailed(%v) = false, want true", err)
+	}
+
+	if err := verify("other"); err != nil {
+		t.Errorf("Verify() with other key error = %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if err := verify("key"); err != nil {
+		t.Errorf("Verify() after window error = %v", err)
+	}
+
+	if calls != 3 {
+		t.Errorf("inner calls = %d, want 3", calls)
+	}
+}
+
+func TestIdempotencyGuardVerifierWithoutKey(t *testing.T) {
+	calls := 0
+	v := NewIdempotencyGuardVerifier(verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+		calls++
+		return &VerificationResponse{Success: true}, nil
+	}), time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if err := verifyToken(v); err != nil {
+			t.Errorf("Verify() error = %v", err)
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("inner calls = %d, want 2", calls)
+	}
+}
+
+func TestIdempotencyGuardVerifierReleasesKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		released bool
+	}{
+		{name: "transport error", err: transportError(errors.New("connection refused")), released: true},
+		{name: "internal error", err: mapErrorCodes([]ErrorCode{CodeInternalError}), released: true},
+		{name: "validation failure", err: mapErrorCodes([]ErrorCode{CodeInvalidInputResponse})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fail := true
+			v := NewIdempotencyGuardVerifier(verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+				if fail {
+					fail = false
+					return nil, tt.err
+				}
+				return &VerificationResponse{Success: true}, nil
+			}), time.Minute)
+
+			req := &VerificationRequest{Response: "token", IdempotencyKey: "key"}
+			if _, err := v.Verify(context.Background(), req); !errors.Is(err, tt.err) {
+				t.Fatalf("first Verify() error = %v, want %v", err, tt.err)
+			}
+
+			_, err := v.Verify(context.Background(), req)
+			if tt.released && err != nil {
+				t.Errorf("retried Verify() error = %v, want nil", err)
+			}
+			if !tt.released && !errors.Is(err, ErrDuplicateIdempotencyKey) {
+				t.Errorf("retried Verify() error = %v, want %v", err, ErrDuplicateIdempotencyKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package turnstile
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// NormalizeRemoteIP strips ports, brackets and IPv6 zone identifiers from
+// addr, as Cloudflare rejects them in remoteip. It returns an empty string if
+// no valid IP remains.
+func NormalizeRemoteIP(addr string) string {
+	addr = strings.TrimSpace(addr)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+	if i := strings.IndexByte(addr, '%'); i >= 0 {
+		addr = addr[:i]
+	}
+
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return ""
+	}
+
+	return ip.String()
+}
+
+// RemoteIPFromRequest returns the client IP of r for use as RemoteIP. With
+// trustProxies set, the right-most address of the X-Forwarded-For chain, i.e.
+// the one added by the proxy in front of the server, is preferred; addresses
+// further left were sent by the client or earlier hops and can be spoofed.
+// Only set it when exactly one trusted proxy appends the client address to
+// that header. If the right-most entry is not a valid IP, or trustProxies is
+// not set, the connection's remote address is used.
+func RemoteIPFromRequest(r *http.Request, trustProxies bool) string {
+	if trustProxies {
+		if headers := r.Header.Values("X-Forwarded-For"); len(headers) > 0 {
+			chain := strings.Split(headers[len(headers)-1], ",")
+			if ip := NormalizeRemoteIP(chain[len(chain)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	return NormalizeRemoteIP(r.RemoteAddr)
+}
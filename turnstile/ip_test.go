@@ -0,0 +1,98 @@
+package turnstile
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNormalizeRemoteIP(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{addr: "1.2.3.4", want: "1.2.3.4"},
+		{addr: "1.2.3.4:5678", want: "1.2.3.4"},
+		{addr: " 1.2.3.4 ", want: "1.2.3.4"},
+		{addr: "::1", want: "::1"},
+		{addr: "[::1]", want: "::1"},
+		{addr: "[::1]:80", want: "::1"},
+		{addr: "fe80::1%eth0", want: "fe80::1"},
+		{addr: "[fe80::1%eth0]:443", want: "fe80::1"},
+		{addr: "", want: ""},
+		{addr: "unknown", want: ""},
+		{addr: "1.2.3.4.5", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeRemoteIP(tt.addr); got != tt.want {
+			t.Errorf("NormalizeRemoteIP(%q) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestRemoteIPFromRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		xff          []string
+		trustProxies bool
+		want         string
+	}{
+		{name: "remote address", remoteAddr: "192.0.2.1:1234", want: "192.0.2.1"},
+		{name: "IPv6 remote address", remoteAddr: "[2001:db8::1]:1234", want: "2001:db8::1"},
+		{name: "untrusted XFF", remoteAddr: "192.0.2.1:1234", xff: []string{"203.0.113.1"}, want: "192.0.2.1"},
+		{name: "trusted XFF", remoteAddr: "192.0.2.1:1234", xff: []string{"203.0.113.1"}, trustProxies: true, want: "203.0.113.1"},
+		{
+			name:         "XFF chain",
+			remoteAddr:   "192.0.2.1:1234",
+			xff:          []string{"203.0.113.1, 198.51.100.1, 192.0.2.10"},
+			trustProxies: true,
+			want:         "192.0.2.10",
+		},
+		{
+			name:         "spoofed left-most entry",
+			remoteAddr:   "192.0.2.1:1234",
+			xff:          []string{"10.0.0.1, 203.0.113.1"},
+			trustProxies: true,
+			want:         "203.0.113.1",
+		},
+		{
+			name:         "port stripped",
+			remoteAddr:   "192.0.2.1:1234",
+			xff:          []string{"unknown, 203.0.113.1:443"},
+			trustProxies: true,
+			want:         "203.0.113.1",
+		},
+		{
+			name:         "invalid right-most entry",
+			remoteAddr:   "192.0.2.1:1234",
+			xff:          []string{"203.0.113.1, unknown"},
+			trustProxies: true,
+			want:         "192.0.2.1",
+		},
+		{
+			name:         "repeated headers",
+			remoteAddr:   "192.0.2.1:1234",
+			xff:          []string{"203.0.113.1", "198.51.100.1"},
+			trustProxies: true,
+			want:         "198.51.100.1",
+		},
+		{name: "no valid XFF", remoteAddr: "192.0.2.1:1234", xff: []string{"unknown"}, trustProxies: true, want: "192.0.2.1"},
+		{name: "unknown", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, xff := range tt.xff {
+				req.Header.Add("X-Forwarded-For", xff)
+			}
+
+			if got := RemoteIPFromRequest(req, tt.trustProxies); got != tt.want {
+				t.Errorf("RemoteIPFromRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// IPTaggedError wraps a verification error with the remote IP of the request
+// that caused it, for correlating failures with their source.
+type IPTaggedError struct {
+	RemoteIP string
+	Err      error
+}
+
+func (e *IPTaggedError) Error() string {
+	return fmt.Sprintf("remote ip %s: %v", e.RemoteIP, e.Err)
+}
+
+func (e *IPTaggedError) Unwrap() error {
+	return e.Err
+}
+
+// RemoteIPFromError returns the remote IP err was tagged with by
+// NewIPTaggingVerifier.
+func RemoteIPFromError(err error) (string, bool) {
+	var tagged *IPTaggedError
+	if !errors.As(err, &tagged) {
+		return "", false
+	}
+
+	return tagged.RemoteIP, true
+}
+
+type ipTaggingVerifier struct {
+	inner Verifier
+}
+
+// NewIPTaggingVerifier wraps errors returned by inner in an IPTaggedError
+// carrying the request's RemoteIP, for abuse analysis by downstream handlers
+// and loggers. Requests without a remote IP are passed through unchanged.
+func NewIPTaggingVerifier(inner Verifier) Verifier {
+	return &ipTaggingVerifier{inner: inner}
+}
+
+func (v *ipTaggingVerifier) Verify(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+	resp, err := v.inner.Verify(ctx, req)
+	if err == nil || req.RemoteIP == "" {
+		return resp, err
+	}
+
+	return resp, &IPTaggedError{RemoteIP: req.RemoteIP, Err: err}
+}
@@ -0,0 +1,50 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIPTaggingVerifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		remoteIP string
+		wantErr  bool
+		wantIP   string
+	}{
+		{name: "failure with IP", secret: TestSecretAlwaysFails, remoteIP: "203.0.113.1", wantErr: true, wantIP: "203.0.113.1"},
+		{name: "failure without IP", secret: TestSecretAlwaysFails, wantErr: true},
+		{name: "success", secret: TestSecretAlwaysPasses, remoteIP: "203.0.113.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewIPTaggingVerifier(NewOfflineVerifier(tt.secret))
+
+			_, err := v.Verify(context.Background(), &VerificationRequest{Response: "token", RemoteIP: tt.remoteIP})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			ip, ok := RemoteIPFromError(fmt.Errorf("handling signup: %w", err))
+			if ip != tt.wantIP || ok != (tt.wantIP != "") {
+				t.Errorf("RemoteIPFromError() = %q, %v, want %q", ip, ok, tt.wantIP)
+			}
+
+			if tt.wantErr && !IsValidationFailed(err) {
+				t.Errorf("IsValidationFailed(%v) = false, want the inner error reachable", err)
+			}
+		})
+	}
+}
+
+func TestRemoteIPFromUntaggedError(t *testing.T) {
+	for _, err := range []error{nil, errors.New("boom")} {
+		if ip, ok := RemoteIPFromError(err); ok || ip != "" {
+			t.Errorf("RemoteIPFromError(%v) = %q, %v, want \"\", false", err, ip, ok)
+		}
+	}
+}
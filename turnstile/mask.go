@@ -0,0 +1,16 @@
+package turnstile
+
+import "strings"
+
+const maskedTokenAffixLen = 4
+
+// maskToken shortens token to its first and last few characters, so it can be
+// recognized in logs and errors without being replayable. Short tokens are
+// masked completely.
+func maskToken(token string) string {
+	if len(token) < 3*maskedTokenAffixLen {
+		return strings.Repeat("*", len(token))
+	}
+
+	return token[:maskedTokenAffixLen] + "..." + token[len(token)-maskedTokenAffixLen:]
+}
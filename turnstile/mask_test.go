@@ -0,0 +1,61 @@
+package turnstile
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaskToken(t *testing.T) {
+	tests := []struct {
+		token string
+		want  string
+	}{
+		{token: "", want: ""},
+		{token: "short", want: "*****"},
+		{token: "0123456789a", want: "***********"},
+		{token: "0123456789ab", want: "0123...89ab"},
+		{token: "0.abcdefghijklmnopqrstuvwxyz", want: "0.ab...wxyz"},
+	}
+
+	for _, tt := range tests {
+		if got := maskToken(tt.token); got != tt.want {
+			t.Errorf("maskToken(%q) = %q, want %q", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestTokenMaskedInOutput(t *testing.T) {
+	const token = "0.abcdefghijklmnopqrstuvwxyz0123456789"
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	verifiers := map[string]Verifier{
+		"too long": NewVerifierClientWithHandler("secret", respondJSON(`{"success":true}`), WithMaxTokenLength(16)),
+		"expired":  NewVerifierClientWithHandler("secret", respondJSON(`{"success":true}`), WithTokenTTL(time.Minute)),
+		"sampled":  NewSamplingLogVerifier(NewOfflineVerifier(TestSecretAlwaysFails), 1, logger),
+	}
+
+	for name, v := range verifiers {
+		t.Run(name, func(t *testing.T) {
+			buf.Reset()
+
+			_, err := v.Verify(context.Background(), &VerificationRequest{Response: token, IssuedAt: time.Now().Add(-time.Hour)})
+			if err == nil {
+				t.Fatal("Verify() error = nil, want an error")
+			}
+
+			out := err.Error() + buf.String()
+			if strings.Contains(out, token) {
+				t.Errorf("output %q contains the full token", out)
+			}
+			if !strings.Contains(out, maskToken(token)) {
+				t.Errorf("output %q lacks the masked token %q", out, maskToken(token))
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package turnstile
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// microBatchTimeout bounds shared calls started by a caller without a
+// deadline.
+const microBatchTimeout = 10 * time.Second
+
+type microBatchCall struct {
+	done  chan struct{}
+	start time.Time
+	resp  *VerificationResponse
+	err   error
+}
+
+type microBatchVerifier struct {
+	inner  Verifier
+	window time.Duration
+
+	mu    sync.Mutex
+	calls map[[sha256.Size]byte]*microBatchCall
+}
+
+// NewMicroBatchVerifier coalesces verifications of identical tokens into a
+// single call to inner while it is in flight, sharing its result with every
+// caller that joined within window of its start. Finished results are never
+// served to later callers, so a single-use token can not be replayed. The
+// shared call is not cancelled with any one caller; a caller whose context is
+// done stops waiting and returns the context error. The shared call keeps the
+// deadline of the caller that started it, or is bounded by a timeout of ten
+// seconds if there is none. Siteverify accepts a
+// single token per request, so distinct tokens can not be batched and are
+// passed through individually.
+func NewMicroBatchVerifier(inner Verifier, window time.Duration) Verifier {
+	return &microBatchVerifier{
+		inner:  inner,
+		window: window,
+		calls:  make(map[[sha256.Size]byte]*microBatchCall),
+	}
+}
+
+func (v *microBatchVerifier) Verify(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+	key := sha256.Sum256([]byte(req.Response))
+
+	v.mu.Lock()
+	call, ok := v.calls[key]
+	if !ok || time.Since(call.start) >= v.window {
+		call = &microBatchCall{done: make(chan struct{}), start: time.Now()}
+		v.calls[key] = call
+		go v.run(ctx, key, call, *req)
+	}
+	v.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if call.resp == nil {
+		return nil, call.err
+	}
+
+	resp := *call.resp
+	return &resp, call.err
+}
+
+// run calls inner, detached from the cancellation of ctx, and forgets call as
+// soon as it finished, so later verifications of the same token reach inner
+// again.
+func (v *microBatchVerifier) run(ctx context.Context, key [sha256.Size]byte, call *microBatchCall, req VerificationRequest) {
+	ctx, cancel := microBatchContext(ctx)
+	defer cancel()
+
+	call.resp, call.err = v.inner.Verify(ctx, &req)
+
+	v.mu.Lock()
+	if v.calls[key] == call {
+		delete(v.calls, key)
+	}
+	v.mu.Unlock()
+
+	close(call.done)
+}
+
+// microBatchContext detaches ctx from its cancellation, keeping its deadline.
+func microBatchContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+
+	return context.WithTimeout(detached, microBatchTimeout)
+}
@@ -0,0 +1,177 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gatedVerifier returns a verifier counting its calls, signalling started
+// when one begins and answering once release is closed.
+func gatedVerifier(calls *atomic.Int32, started chan<- struct{}, release <-chan struct{}) Verifier {
+	return verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+		calls.Add(1)
+		started <- struct{}{}
+
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		return &VerificationResponse{Success: true, Hostname: req.Response}, nil
+	})
+}
+
+func TestMicroBatchVerifierSharesInFlightCall(t *testing.T) {
+	var calls atomic.Int32
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	v := NewMicroBatchVerifier(gatedVerifier(&calls, started, release), time.Minute)
+
+	const callers = 5
+
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			resp, err := v.Verify(context.Background(), &VerificationRequest{Response: "token"})
+			if err == nil && (resp == nil || resp.Hostname != "token") {
+				err = errors.New("unexpected response")
+			}
+			errs <- err
+		}()
+	}
+
+	<-started
+	// Give the remaining callers time to join the in-flight call.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Verify() error = %v", err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("inner called %d times, want 1", got)
+	}
+}
+
+func TestMicroBatchVerifierLeaderCancellation(t *testing.T) {
+	var calls atomic.Int32
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	v := NewMicroBatchVerifier(gatedVerifier(&calls, started, release), time.Minute)
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := v.Verify(leaderCtx, &VerificationRequest{Response: "token"})
+		leaderErr <- err
+	}()
+	<-started
+
+	followerErr := make(chan error, 1)
+	go func() {
+		_, err := v.Verify(context.Background(), &VerificationRequest{Response: "token"})
+		followerErr <- err
+	}()
+	// Give the follower time to join the in-flight call.
+	time.Sleep(20 * time.Millisecond)
+
+	cancelLeader()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("Verify() of the leader error = %v, want context.Canceled", err)
+	}
+
+	close(release)
+	if err := <-followerErr; err != nil {
+		t.Errorf("Verify() of the follower error = %v, want nil", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("inner called %d times, want 1", got)
+	}
+}
+
+func TestMicroBatchVerifierNoReplayAfterCompletion(t *testing.T) {
+	var calls atomic.Int32
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	close(release)
+	v := NewMicroBatchVerifier(gatedVerifier(&calls, started, release), time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if err := verifyToken(v); err != nil {
+			t.Fatalf("Verify() error = %v", err)
+		}
+	}
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("inner called %d times for sequential verifications, want 2", got)
+	}
+}
+
+func TestMicroBatchVerifierDistinctTokens(t *testing.T) {
+	var calls atomic.Int32
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	close(release)
+	v := NewMicroBatchVerifier(gatedVerifier(&calls, started, release), time.Minute)
+
+	for _, token := range []string{"a", "b"} {
+		resp, err := v.Verify(context.Background(), &VerificationRequest{Response: token})
+		if err != nil || resp.Hostname != token {
+			t.Errorf("Verify(%q) = %+v, %v", token, resp, err)
+		}
+	}
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("inner called %d times, want 2", got)
+	}
+}
+
+func TestMicroBatchVerifierKeepsDeadline(t *testing.T) {
+	tests := []struct {
+		name     string
+		deadline time.Duration
+		want     time.Duration
+	}{
+		{name: "caller deadline", deadline: time.Minute, want: time.Minute},
+		{name: "no deadline", want: microBatchTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deadlines := make(chan time.Duration, 1)
+			v := NewMicroBatchVerifier(verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+				deadline, ok := ctx.Deadline()
+				if !ok {
+					deadlines <- 0
+				} else {
+					deadlines <- time.Until(deadline)
+				}
+				return &VerificationResponse{Success: true}, nil
+			}), time.Second)
+
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			if _, err := v.Verify(ctx, &VerificationRequest{Response: "token"}); err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			if got := <-deadlines; got <= tt.want-time.Second || got > tt.want {
+				t.Errorf("inner deadline in %v, want about %v", got, tt.want)
+			}
+		})
+	}
+}
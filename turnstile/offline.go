@@ -0,0 +1,53 @@
+package turnstile
+
+import (
+	"context"
+	"time"
+)
+
+// Secret keys and token documented by Cloudflare for testing.
+const (
+	TestSecretAlwaysPasses = "1x0000000000000000000000000000000AA"
+	TestSecretAlwaysFails  = "2x0000000000000000000000000000000AA"
+	TestSecretTokenSpent   = "3x0000000000000000000000000000000AA"
+	DummyToken             = "XXXX.DUMMY.TOKEN.XXXX"
+)
+
+const testHostname = "example.com"
+
+type offlineVerifier struct {
+	secret string
+}
+
+// NewOfflineVerifier returns a Verifier that answers without network access
+// the way siteverify answers for Cloudflare's test secret keys. Missing
+// secrets and tokens and any other secret yield the matching error codes, so
+// tests can exercise failure paths without mocking HTTP.
+func NewOfflineVerifier(secret string) Verifier {
+	return &offlineVerifier{secret: secret}
+}
+
+func (v *offlineVerifier) Verify(_ context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+	var codes []ErrorCode
+	switch {
+	case v.secret == "":
+		codes = []ErrorCode{CodeMissingInputSecret}
+	case req.Response == "":
+		codes = []ErrorCode{CodeMissingInputResponse}
+	case v.secret == TestSecretAlwaysPasses:
+		return &VerificationResponse{
+			Success:     true,
+			ChallengeTs: time.Now().UTC(),
+			Hostname:    testHostname,
+			ErrorCodes:  []ErrorCode{},
+		}, nil
+	case v.secret == TestSecretAlwaysFails:
+		codes = []ErrorCode{CodeInvalidInputResponse}
+	case v.secret == TestSecretTokenSpent:
+		codes = []ErrorCode{CodeTimeoutOrDuplicate}
+	default:
+		codes = []ErrorCode{CodeInvalidInputSecret}
+	}
+
+	return &VerificationResponse{ErrorCodes: codes}, mapErrorCodes(codes)
+}
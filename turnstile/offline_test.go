@@ -0,0 +1,49 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestOfflineVerifier(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		token  string
+		codes  []ErrorCode
+		kind   OutcomeKind
+	}{
+		{name: "always passes", secret: TestSecretAlwaysPasses, token: DummyToken, kind: OutcomeKindSuccess},
+		{name: "always fails", secret: TestSecretAlwaysFails, token: DummyToken, codes: []ErrorCode{CodeInvalidInputResponse}, kind: OutcomeKindValidationFailure},
+		{name: "token spent", secret: TestSecretTokenSpent, token: DummyToken, codes: []ErrorCode{CodeTimeoutOrDuplicate}, kind: OutcomeKindValidationFailure},
+		{name: "unknown secret", secret: "secret", token: DummyToken, codes: []ErrorCode{CodeInvalidInputSecret}, kind: OutcomeKindInvalidRequest},
+		{name: "missing secret", token: DummyToken, codes: []ErrorCode{CodeMissingInputSecret}, kind: OutcomeKindInvalidRequest},
+		{name: "missing token", secret: TestSecretAlwaysPasses, codes: []ErrorCode{CodeMissingInputResponse}, kind: OutcomeKindInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := NewOfflineVerifier(tt.secret).Verify(context.Background(), &VerificationRequest{Response: tt.token})
+
+			if got := KindOf(err); got != tt.kind {
+				t.Errorf("KindOf(%v) = %v, want %v", err, got, tt.kind)
+			}
+			if resp == nil {
+				t.Fatal("Verify() response = nil")
+			}
+			if resp.Success != (tt.kind == OutcomeKindSuccess) {
+				t.Errorf("Success = %v, want %v", resp.Success, tt.kind == OutcomeKindSuccess)
+			}
+			if len(tt.codes) > 0 && !slices.Equal(resp.ErrorCodes, tt.codes) {
+				t.Errorf("ErrorCodes = %v, want %v", resp.ErrorCodes, tt.codes)
+			}
+
+			var verr *VerificationError
+			if len(tt.codes) > 0 && (!errors.As(err, &verr) || !slices.Equal(verr.ErrorCodes, tt.codes)) {
+				t.Errorf("Verify() error = %v, want a VerificationError with codes %v", err, tt.codes)
+			}
+		})
+	}
+}
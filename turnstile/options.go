@@ -0,0 +1,281 @@
+package turnstile
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+)
+
+type Option func(t *verifierClient)
+
+// WithHTTPClient uses the given client for siteverify calls. Options that tune
+// the default transport have no effect when a custom client is supplied.
+func WithHTTPClient(client *http.Client) Option {
+	return func(t *verifierClient) {
+		t.customClient = client
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the default transport, e.g. to
+// pin certificates or enforce a minimum TLS version.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(t *verifierClient) {
+		t.tlsConfig = cfg
+	}
+}
+
+// WithDialContext sets the dial function of the default transport, e.g. to
+// route siteverify traffic through a specific interface or a SOCKS proxy for
+// egress control.
+func WithDialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) Option {
+	return func(t *verifierClient) {
+		t.dialContext = dial
+	}
+}
+
+// WithAcceptHeader sets the Accept header of siteverify requests. Defaults to
+// application/json; an empty value omits the header.
+func WithAcceptHeader(value string) Option {
+	return func(t *verifierClient) {
+		t.acceptHeader = value
+	}
+}
+
+// WithRequireRemoteIP rejects requests without a RemoteIP with ErrInvalidRequest
+// before calling Cloudflare, catching proxies that do not forward client IPs.
+func WithRequireRemoteIP() Option {
+	return func(t *verifierClient) {
+		t.requireRemoteIP = true
+	}
+}
+
+// WithRetry retries transport errors, HTTP 429 and 5xx responses and Cloudflare
+// internal errors up to maxAttempts calls in total. Delays grow exponentially
+// from baseDelay; a Retry-After header sent by the server takes precedence.
+// All delays are capped at maxDelay.
+//
+// Cloudflare only accepts a token once, so retries should be combined with an
+// idempotency key: without one, a retry of a request that reached Cloudflare
+// fails with timeout-or-duplicate.
+func WithRetry(maxAttempts int, baseDelay, maxDelay time.Duration) Option {
+	return func(t *verifierClient) {
+		t.maxAttempts = max(maxAttempts, 1)
+		t.retryBaseDelay = baseDelay
+		t.retryMaxDelay = maxDelay
+	}
+}
+
+// WithBackoff replaces the exponential delays of WithRetry with backoff, e.g.
+// DecorrelatedJitterBackoff. A Retry-After header still takes precedence and
+// delays remain capped at the maxDelay passed to WithRetry.
+func WithBackoff(backoff Backoff) Option {
+	return func(t *verifierClient) {
+		t.backoff = backoff
+	}
+}
+
+// WithRawResponseSink passes a copy of every siteverify response body to sink
+// before it is decoded, which helps debugging integrations. Response bodies
+// never contain the secret.
+func WithRawResponseSink(sink func([]byte)) Option {
+	return func(t *verifierClient) {
+		t.rawResponseSink = sink
+	}
+}
+
+// WithTokenDerivedIdempotencyKey derives the idempotency key from a hash of
+// the token when the request carries none, so repeated verifications of the
+// same token are deduplicated by Cloudflare.
+func WithTokenDerivedIdempotencyKey() Option {
+	return func(t *verifierClient) {
+		t.deriveIdempotencyKey = true
+	}
+}
+
+// WithMaxTokenLength rejects tokens longer than n bytes with ErrTokenTooLong
+// before calling Cloudflare. Defaults to DefaultMaxTokenLength; n <= 0
+// disables the check.
+func WithMaxTokenLength(n int) Option {
+	return func(t *verifierClient) {
+		t.maxTokenLength = n
+	}
+}
+
+// WithBatchConcurrency sets the number of verifications VerifyFromReader runs
+// at once. Defaults to DefaultBatchConcurrency; n < 1 is treated as 1.
+func WithBatchConcurrency(n int) Option {
+	return func(t *verifierClient) {
+		t.batchConcurrency = n
+	}
+}
+
+// WithInsecureSkipVerify disables certificate verification on the default
+// transport. It is meant for tests against local mocks with self-signed
+// certificates only and must never be used in production.
+func WithInsecureSkipVerify() Option {
+	return func(t *verifierClient) {
+		t.insecureSkipVerify = true
+	}
+}
+
+// WithExpectedAction rejects responses whose action differs from action with
+// ErrActionMismatch.
+func WithExpectedAction(action string) Option {
+	if action == "" {
+		return WithAllowedActions()
+	}
+
+	return WithAllowedActions(action)
+}
+
+// WithAllowedActions rejects responses whose action is not one of actions with
+// ErrActionMismatch, for widgets used for several purposes. No actions disable
+// the check.
+func WithAllowedActions(actions ...string) Option {
+	return func(t *verifierClient) {
+		t.allowedActions = slices.Clone(actions)
+	}
+}
+
+// WithAllowedHostnames rejects responses whose hostname is not one of
+// hostnames with ErrHostnameNotAllowed. Hostnames are compared ignoring case.
+// Entries starting with "." or "*.", such as ".example.com", match any
+// subdomain of the rest of the entry but not the domain itself; list
+// "example.com" too to allow it. Subdomain patterns broader than a domain with
+// at least two labels, such as "." or "*.com", reject every response with
+// ErrHostnameNotAllowed.
+func WithAllowedHostnames(hostnames ...string) Option {
+	return func(t *verifierClient) {
+		t.allowedHostnames = slices.Clone(hostnames)
+		t.hostnamePatternErr = nil
+		for _, hostname := range hostnames {
+			if err := validateHostnamePattern(hostname); err != nil {
+				t.hostnamePatternErr = err
+				break
+			}
+		}
+	}
+}
+
+// WithHostnameValidator calls validate with the hostname of every successful
+// response, for allowlists that are not known up front, e.g. tenant hostnames
+// stored in a database. A returned error rejects the verification with an
+// error wrapping both ErrHostnameNotAllowed and the returned error. It runs
+// after the WithAllowedHostnames check, and only if that passed.
+func WithHostnameValidator(validate func(ctx context.Context, hostname string) error) Option {
+	return func(t *verifierClient) {
+		t.hostnameValidator = validate
+	}
+}
+
+// WithMaxChallengeAge rejects responses whose challenge was solved more than
+// maxAge ago with ErrChallengeExpired.
+func WithMaxChallengeAge(maxAge time.Duration) Option {
+	return func(t *verifierClient) {
+		t.maxChallengeAge = maxAge
+	}
+}
+
+// WithVerifyStats records the client's verifications and HTTP attempts in
+// stats.
+func WithVerifyStats(stats *VerifyStats) Option {
+	return func(t *verifierClient) {
+		t.stats = stats
+	}
+}
+
+// WithJSONCodec replaces encoding/json for encoding requests and decoding
+// responses, e.g. with a faster JSON library.
+func WithJSONCodec(codec JSONCodec) Option {
+	return func(t *verifierClient) {
+		t.jsonCodec = codec
+	}
+}
+
+// WithTokenTTL fails requests whose IssuedAt lies more than ttl in the past
+// with ErrChallengeExpired, without calling Cloudflare. Unlike
+// WithMaxChallengeAge it acts before the round-trip and relies on the issuance
+// time reported by the client. DefaultTokenTTL matches Cloudflare's token
+// validity.
+func WithTokenTTL(ttl time.Duration) Option {
+	return func(t *verifierClient) {
+		t.tokenTTL = ttl
+	}
+}
+
+// WithEventSink publishes a VerifyEvent for every verification, e.g. to an
+// audit bus. The sink is called synchronously on the verification path, so it
+// must not block: hand events off to a buffered channel or goroutine.
+func WithEventSink(sink func(ctx context.Context, e VerifyEvent)) Option {
+	return func(t *verifierClient) {
+		t.eventSink = sink
+	}
+}
+
+// WithRequestBuilder replaces how siteverify requests are built, e.g. with
+// QueryRequestBuilder for verification proxies expecting GET requests.
+// Defaults to Cloudflare's JSON POST.
+func WithRequestBuilder(builder RequestBuilder) Option {
+	return func(t *verifierClient) {
+		t.requestBuilder = builder
+	}
+}
+
+// WithStrictMode additionally requires successful responses to carry no error
+// codes and a hostname. Checks apply in order of precedence: a response
+// without success fails with its VerificationError alone; otherwise every
+// failed check, including the inconsistent-response check
+// (ErrInconsistentResponse), the hostname and the other configured
+// expectations, is reported together in one ExpectationError.
+func WithStrictMode() Option {
+	return func(t *verifierClient) {
+		t.strictMode = true
+	}
+}
+
+// WithTracePropagation calls inject with the context and headers of every
+// outgoing siteverify request, so trace headers such as traceparent and
+// baggage can be added. With OpenTelemetry:
+//
+//	turnstile.WithTracePropagation(func(ctx context.Context, h http.Header) {
+//		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(h))
+//	})
+func WithTracePropagation(inject func(ctx context.Context, header http.Header)) Option {
+	return func(t *verifierClient) {
+		t.tracePropagator = inject
+	}
+}
+
+// WithCollapseErrorsToValidationFailed reports every failed verification as a
+// validation failure, for consumers treating any failure as "challenge
+// again": transport errors and rejected requests then satisfy
+// errors.Is(err, ErrValidationFailed) and KindOf reports
+// OutcomeKindValidationFailure. The original error is still reachable through
+// errors.Is and errors.As, so its error codes keep driving predicates such as
+// IsClientCorrectable and IsTimeoutOrDuplicate.
+func WithCollapseErrorsToValidationFailed() Option {
+	return func(t *verifierClient) {
+		t.collapseErrors = true
+	}
+}
+
+// WithTotalTimeout bounds a whole verification, including every retry and the
+// delays between them. Once it expires, Verify fails with an error wrapping
+// context.DeadlineExceeded.
+func WithTotalTimeout(d time.Duration) Option {
+	return func(t *verifierClient) {
+		t.totalTimeout = d
+	}
+}
+
+// WithPerRequestTimeout bounds each attempt at calling siteverify. An attempt
+// timing out counts as a transport error and is retried as configured by
+// WithRetry.
+func WithPerRequestTimeout(d time.Duration) Option {
+	return func(t *verifierClient) {
+		t.perRequestTimeout = d
+	}
+}
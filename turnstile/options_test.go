@@ -0,0 +1,362 @@
+package turnstile
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithTLSConfig(t *testing.T) {
+	client := NewVerifierClient("secret", WithTLSConfig(&tls.Config{MinVersion: tls.VersionTLS13})).(*verifierClient)
+
+	transport, ok := client.httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("transport = %T, want *http.Transport", client.httpClient.Transport)
+	}
+
+	if got := transport.TLSClientConfig.MinVersion; got != tls.VersionTLS13 {
+		t.Errorf("MinVersion = %x, want %x", got, tls.VersionTLS13)
+	}
+}
+
+func TestWithTLSConfigDefault(t *testing.T) {
+	client := NewVerifierClient("secret").(*verifierClient)
+
+	if client.httpClient.Transport != nil {
+		t.Errorf("transport = %T, want nil to use Go's defaults", client.httpClient.Transport)
+	}
+}
+
+func TestWithTLSConfigCustomClient(t *testing.T) {
+	custom := &http.Client{}
+	client := NewVerifierClient("secret", WithHTTPClient(custom), WithTLSConfig(&tls.Config{MinVersion: tls.VersionTLS13})).(*verifierClient)
+
+	if client.httpClient != custom {
+		t.Error("custom client was replaced")
+	}
+}
+
+func TestWithRequireRemoteIP(t *testing.T) {
+	var calls int
+	srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		respondJSON(`{"success":true}`)(w, r)
+	})
+
+	v := NewVerifierClientWithURL("secret", srv.URL, WithRequireRemoteIP())
+
+	_, err := v.Verify(context.Background(), &VerificationRequest{Response: "token"})
+	if !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Verify() without remote IP error = %v, want ErrInvalidRequest", err)
+	}
+	if calls != 0 {
+		t.Errorf("siteverify called %d times without remote IP, want 0", calls)
+	}
+
+	if _, err := v.Verify(context.Background(), &VerificationRequest{Response: "token", RemoteIP: "203.0.113.1"}); err != nil {
+		t.Errorf("Verify() with remote IP error = %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("siteverify called %d times with remote IP, want 1", calls)
+	}
+}
+
+func TestWithRawResponseSink(t *testing.T) {
+	const body = "{\"success\": true,  \"hostname\":\"example.com\", \"extra\": [1, 2]}\n"
+
+	srv := newSiteverifyServer(t, respondJSON(body))
+
+	var got []byte
+	v := NewVerifierClientWithURL("secret", srv.URL, WithRawResponseSink(func(raw []byte) {
+		got = raw
+	}))
+
+	resp, err := v.Verify(context.Background(), &VerificationRequest{Response: "token"})
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if string(got) != body {
+		t.Errorf("sink received %q, want %q", got, body)
+	}
+	if resp.Hostname != "example.com" {
+		t.Errorf("Hostname = %q, want %q", resp.Hostname, "example.com")
+	}
+}
+
+func TestWithOptions(t *testing.T) {
+	srv := newSiteverifyServer(t, respondJSON(`{"success":true,"action":"signup"}`))
+
+	base := NewVerifierClientWithURL("secret", srv.URL, WithExpectedAction("login"))
+	clone := base.(interface{ WithOptions(...Option) Verifier }).WithOptions(WithExpectedAction("signup"))
+
+	if err := verifyToken(clone); err != nil {
+		t.Errorf("Verify() with the clone error = %v", err)
+	}
+	if err := verifyToken(base); !errors.Is(err, ErrValidationFailed) {
+		t.Errorf("Verify() with the original error = %v, want ErrValidationFailed", err)
+	}
+	if clone.(URLProvider).URL() != srv.URL {
+		t.Errorf("clone URL = %q, want %q", clone.(URLProvider).URL(), srv.URL)
+	}
+}
+
+func TestWithMaxTokenLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		length  int
+		wantErr bool
+	}{
+		{name: "default valid", length: DefaultMaxTokenLength},
+		{name: "default over length", length: DefaultMaxTokenLength + 1, wantErr: true},
+		{name: "custom valid", opts: []Option{WithMaxTokenLength(16)}, length: 16},
+		{name: "custom over length", opts: []Option{WithMaxTokenLength(16)}, length: 17, wantErr: true},
+		{name: "disabled", opts: []Option{WithMaxTokenLength(0)}, length: 10 * DefaultMaxTokenLength},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			v := NewVerifierClientWithHandler("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				respondJSON(`{"success":true}`)(w, r)
+			}), tt.opts...)
+
+			_, err := v.Verify(context.Background(), &VerificationRequest{Response: strings.Repeat("x", tt.length)})
+
+			if !tt.wantErr {
+				if err != nil || calls != 1 {
+					t.Errorf("Verify() error = %v with %d calls, want nil with 1 call", err, calls)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrTokenTooLong) || !errors.Is(err, ErrInvalidRequest) {
+				t.Errorf("Verify() error = %v, want ErrTokenTooLong matching ErrInvalidRequest", err)
+			}
+			if got := KindOf(err); got != OutcomeKindValidationFailure {
+				t.Errorf("KindOf(%v) = %v, want %v", err, got, OutcomeKindValidationFailure)
+			}
+			if strings.Contains(err.Error(), strings.Repeat("x", 16)) {
+				t.Errorf("Verify() error = %q, want the token masked", err)
+			}
+			if calls != 0 {
+				t.Errorf("siteverify called %d times, want 0", calls)
+			}
+		})
+	}
+}
+
+func TestWithInsecureSkipVerify(t *testing.T) {
+	srv := httptest.NewTLSServer(respondJSON(`{"success":true}`))
+	t.Cleanup(srv.Close)
+
+	if err := verifyToken(NewVerifierClientWithURL("secret", srv.URL, WithInsecureSkipVerify())); err != nil {
+		t.Errorf("Verify() with WithInsecureSkipVerify error = %v", err)
+	}
+
+	err := verifyToken(NewVerifierClientWithURL("secret", srv.URL))
+	var certErr *tls.CertificateVerificationError
+	if KindOf(err) != OutcomeKindTransportError || !errors.As(err, &certErr) {
+		t.Errorf("Verify() without WithInsecureSkipVerify error = %v, want a certificate verification error", err)
+	}
+}
+
+func TestWithTokenTTL(t *testing.T) {
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		wantErr  bool
+	}{
+		{name: "fresh", issuedAt: time.Now().Add(-time.Minute)},
+		{name: "stale", issuedAt: time.Now().Add(-DefaultTokenTTL - time.Minute), wantErr: true},
+		{name: "unknown", issuedAt: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			v := NewVerifierClientWithHandler("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				respondJSON(`{"success":true}`)(w, r)
+			}), WithTokenTTL(DefaultTokenTTL))
+
+			_, err := v.Verify(context.Background(), &VerificationRequest{Response: "token", IssuedAt: tt.issuedAt})
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrChallengeExpired) || calls != 0 {
+					t.Errorf("Verify() error = %v with %d calls, want ErrChallengeExpired without calling siteverify", err, calls)
+				}
+				return
+			}
+
+			if err != nil || calls != 1 {
+				t.Errorf("Verify() error = %v with %d calls, want nil with 1 call", err, calls)
+			}
+		})
+	}
+}
+
+type traceparentKey struct{}
+
+func TestWithTracePropagation(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	var got string
+	srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("traceparent")
+		respondJSON(`{"success":true}`)(w, r)
+	})
+
+	v := NewVerifierClientWithURL("secret", srv.URL, WithTracePropagation(func(ctx context.Context, header http.Header) {
+		if tp, ok := ctx.Value(traceparentKey{}).(string); ok {
+			header.Set("traceparent", tp)
+		}
+	}))
+
+	ctx := context.WithValue(context.Background(), traceparentKey{}, traceparent)
+	if _, err := v.Verify(ctx, &VerificationRequest{Response: "token"}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	if got != traceparent {
+		t.Errorf("traceparent = %q, want %q", got, traceparent)
+	}
+}
+
+func TestWithoutTracePropagation(t *testing.T) {
+	srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if tp := r.Header.Get("traceparent"); tp != "" {
+			t.Errorf("traceparent = %q sent, want none", tp)
+		}
+		respondJSON(`{"success":true}`)(w, r)
+	})
+
+	if err := verifyToken(NewVerifierClientWithURL("secret", srv.URL)); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+func TestWithCollapseErrorsToValidationFailed(t *testing.T) {
+	closed := newSiteverifyServer(t, respondJSON(`{"success":true}`))
+	closed.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		handler http.HandlerFunc
+		inner   OutcomeKind
+	}{
+		{name: "transport error", url: closed.URL, inner: OutcomeKindTransportError},
+		{name: "unavailable", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}, inner: OutcomeKindTransportError},
+		{name: "invalid secret", handler: respondJSON(`{"success":false,"error-codes":["invalid-input-secret"]}`), inner: OutcomeKindInvalidRequest},
+		{name: "validation failure", handler: respondJSON(`{"success":false,"error-codes":["invalid-input-response"]}`), inner: OutcomeKindValidationFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := tt.url
+			if tt.handler != nil {
+				url = newSiteverifyServer(t, tt.handler).URL
+			}
+
+			err := verifyToken(NewVerifierClientWithURL("secret", url, WithCollapseErrorsToValidationFailed()))
+			if !errors.Is(err, ErrValidationFailed) {
+				t.Errorf("Verify() error = %v, want %v", err, ErrValidationFailed)
+			}
+			if got := KindOf(err); got != OutcomeKindValidationFailure {
+				t.Errorf("KindOf(%v) = %v, want %v", err, got, OutcomeKindValidationFailure)
+			}
+
+			if tt.inner == OutcomeKindInvalidRequest && !IsInvalidRequest(err) {
+				t.Errorf("IsInvalidRequest(%v) = false, want the original error reachable", err)
+			}
+
+			if got := KindOf(verifyToken(NewVerifierClientWithURL("secret", url))); got != tt.inner {
+				t.Errorf("KindOf() without the option = %v, want %v", got, tt.inner)
+			}
+		})
+	}
+}
+
+func TestWithDialContext(t *testing.T) {
+	srv := newSiteverifyServer(t, respondJSON(`{"success":true}`))
+
+	var dialed []string
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+
+	v := NewVerifierClientWithURL("secret", "http://siteverify.invalid/siteverify", WithDialContext(dial))
+	if err := verifyToken(v); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	if len(dialed) != 1 || dialed[0] != "siteverify.invalid:80" {
+		t.Errorf("dialed %v, want [siteverify.invalid:80]", dialed)
+	}
+}
+
+func TestWithDialContextIgnoredWithCustomClient(t *testing.T) {
+	srv := newSiteverifyServer(t, respondJSON(`{"success":true}`))
+
+	dialed := false
+	v := NewVerifierClientWithURL("secret", srv.URL,
+		WithHTTPClient(&http.Client{}),
+		WithDialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed = true
+			return nil, errors.New("unexpected dial")
+		}),
+	)
+
+	if err := verifyToken(v); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if dialed {
+		t.Error("dialer invoked despite a custom HTTP client")
+	}
+}
+
+func TestWithCollapseErrorsKeepsErrorCodes(t *testing.T) {
+	tests := []struct {
+		name               string
+		codes              string
+		clientCorrectable  bool
+		timeoutOrDuplicate bool
+	}{
+		{name: "missing input response", codes: `["missing-input-response"]`, clientCorrectable: true},
+		{name: "timeout or duplicate", codes: `["timeout-or-duplicate"]`, clientCorrectable: true, timeoutOrDuplicate: true},
+		{name: "invalid secret", codes: `["invalid-input-secret"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSiteverifyServer(t, respondJSON(`{"success":false,"error-codes":`+tt.codes+`}`))
+
+			err := verifyToken(NewVerifierClientWithURL("secret", srv.URL, WithCollapseErrorsToValidationFailed()))
+			if got := IsClientCorrectable(err); got != tt.clientCorrectable {
+				t.Errorf("IsClientCorrectable(%v) = %v, want %v", err, got, tt.clientCorrectable)
+			}
+			if got := IsTimeoutOrDuplicate(err); got != tt.timeoutOrDuplicate {
+				t.Errorf("IsTimeoutOrDuplicate(%v) = %v, want %v", err, got, tt.timeoutOrDuplicate)
+			}
+
+			var verr *VerificationError
+			if !errors.As(err, &verr) || len(verr.ErrorCodes) != 1 {
+				t.Errorf("Verify() error = %v, want a *VerificationError carrying the error codes", err)
+			}
+			if got := KindOf(err); got != OutcomeKindValidationFailure {
+				t.Errorf("KindOf(%v) = %v, want %v", err, got, OutcomeKindValidationFailure)
+			}
+		})
+	}
+}
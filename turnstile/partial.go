@@ -0,0 +1,48 @@
+package turnstile
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// decodePartialResponse salvages the top-level success and error-codes fields
+// from a response that failed to decode, for example because it was truncated.
+// It returns nil if neither field could be read.
+func decodePartialResponse(data []byte) *VerificationResponse {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+
+	var resp VerificationResponse
+	found := false
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			break
+		}
+
+		var dst any = &json.RawMessage{}
+		switch key {
+		case "success":
+			dst = &resp.Success
+		case "error-codes":
+			dst = &resp.ErrorCodes
+		}
+
+		if err := dec.Decode(dst); err != nil {
+			break
+		}
+
+		if _, skipped := dst.(*json.RawMessage); !skipped {
+			found = true
+		}
+	}
+
+	if !found {
+		return nil
+	}
+
+	return &resp
+}
@@ -0,0 +1,57 @@
+package turnstile
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestDecodePartialResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantNil bool
+		success bool
+		codes   []ErrorCode
+	}{
+		{name: "truncated after success", data: `{"success":true,"hostname":"exam`, success: true},
+		{name: "truncated after codes", data: `{"success":false,"error-codes":["timeout-or-duplicate"],"cha`, codes: []ErrorCode{CodeTimeoutOrDuplicate}},
+		{name: "fields after skipped ones", data: `{"hostname":"example.com","success":true,"action":`, success: true},
+		{name: "truncated before success", data: `{"hostname":"example.com","suc`, wantNil: true},
+		{name: "not an object", data: `["success"]`, wantNil: true},
+		{name: "empty", data: ``, wantNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := decodePartialResponse([]byte(tt.data))
+
+			if tt.wantNil {
+				if resp != nil {
+					t.Errorf("decodePartialResponse() = %+v, want nil", resp)
+				}
+				return
+			}
+
+			if resp == nil {
+				t.Fatal("decodePartialResponse() = nil, want a partial response")
+			}
+			if resp.Success != tt.success || !slices.Equal(resp.ErrorCodes, tt.codes) {
+				t.Errorf("decodePartialResponse() = %+v, want success %v and codes %v", resp, tt.success, tt.codes)
+			}
+		})
+	}
+}
+
+func TestVerifyTruncatedResponse(t *testing.T) {
+	srv := newSiteverifyServer(t, respondJSON(`{"success":true,"challenge_ts":"2024-01-01T00:00:00Z","hostn`))
+
+	resp, err := NewVerifierClientWithURL("secret", srv.URL).Verify(context.Background(), &VerificationRequest{Response: "token"})
+
+	if KindOf(err) != OutcomeKindTransportError {
+		t.Errorf("Verify() error = %v, want a transport error", err)
+	}
+	if resp == nil || !resp.Success {
+		t.Errorf("Verify() response = %+v, want the partial response with Success", resp)
+	}
+}
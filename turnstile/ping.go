@@ -0,0 +1,36 @@
+package turnstile
+
+import (
+	"context"
+	"fmt"
+)
+
+// Pinger is implemented by the Verifier returned by the constructors, so
+// startup code can check the configuration through a type assertion.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping verifies DummyToken once to check that siteverify is reachable and
+// accepts the secret, failing fast at startup instead of on the first user.
+// The token itself is expected to be rejected; any other outcome, such as a
+// transport failure or the invalid-input-secret code, is reported. This is
+// best-effort: a passing Ping does not guarantee later verifications work.
+func (t *verifierClient) Ping(ctx context.Context) error {
+	secret, err := t.secretFunc(ctx)
+	if err != nil {
+		return fmt.Errorf("can not get turnstile secret: %w", err)
+	}
+
+	_, err = t.post(ctx, secret, &VerificationRequest{Response: DummyToken})
+	if err == nil {
+		return nil
+	}
+
+	// A rejected token means siteverify accepted the secret.
+	if KindOf(err) == OutcomeKindValidationFailure {
+		return nil
+	}
+
+	return fmt.Errorf("pinging turnstile: %w", err)
+}
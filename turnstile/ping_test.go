@@ -0,0 +1,63 @@
+package turnstile
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestPing(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		wantErr bool
+	}{
+		{name: "token rejected", body: `{"success":false,"error-codes":["invalid-input-response"]}`, status: http.StatusOK},
+		{name: "token accepted", body: `{"success":true}`, status: http.StatusOK},
+		{name: "invalid secret", body: `{"success":false,"error-codes":["invalid-input-secret"]}`, status: http.StatusOK, wantErr: true},
+		{name: "unavailable", status: http.StatusServiceUnavailable, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var token string
+			srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+				var body VerificationRequest
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("Decode() error = %v", err)
+				}
+				token = body.Response
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			v := NewVerifierClientWithURL("secret", srv.URL)
+			pinger, ok := v.(Pinger)
+			if !ok {
+				t.Fatalf("%T does not implement Pinger", v)
+			}
+
+			err := pinger.Ping(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Ping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if token != DummyToken {
+				t.Errorf("token = %q, want %q", token, DummyToken)
+			}
+		})
+	}
+}
+
+func TestPingInvalidSecretIsInvalidRequest(t *testing.T) {
+	srv := newSiteverifyServer(t, respondJSON(`{"success":false,"error-codes":["invalid-input-secret"]}`))
+
+	err := NewVerifierClientWithURL("secret", srv.URL).(Pinger).Ping(context.Background())
+	if !IsInvalidRequest(err) {
+		t.Errorf("IsInvalidRequest(%v) = false, want true", err)
+	}
+}
@@ -0,0 +1,71 @@
+package turnstile
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+var requestBodyPool = sync.Pool{
+	New: func() any {
+		return &pooledRequestBody{}
+	},
+}
+
+// pooledRequestBody holds an encoded request in a pooled buffer. The transport
+// may close a request body asynchronously after Do returns and may ask for
+// fresh copies through GetBody while Do runs, so the buffer is reference
+// counted: the request itself holds one reference until Do returned, see
+// releaseRequestBody, and every reader handed to the transport holds another.
+type pooledRequestBody struct {
+	buf  bytes.Buffer
+	refs atomic.Int32
+}
+
+func newPooledRequestBody() *pooledRequestBody {
+	b := requestBodyPool.Get().(*pooledRequestBody)
+	b.refs.Store(1)
+
+	return b
+}
+
+func (b *pooledRequestBody) newReader() *pooledBodyReader {
+	b.refs.Add(1)
+
+	r := &pooledBodyReader{body: b}
+	r.Reset(b.buf.Bytes())
+
+	return r
+}
+
+func (b *pooledRequestBody) getBody() (io.ReadCloser, error) {
+	return b.newReader(), nil
+}
+
+func (b *pooledRequestBody) release() {
+	if b.refs.Add(-1) == 0 {
+		b.buf.Reset()
+		requestBodyPool.Put(b)
+	}
+}
+
+type pooledBodyReader struct {
+	bytes.Reader
+	body *pooledRequestBody
+	once sync.Once
+}
+
+func (r *pooledBodyReader) Close() error {
+	r.once.Do(r.body.release)
+	return nil
+}
+
+// releaseRequestBody drops the reference a request built on a pooled body
+// holds, once Do returned and GetBody can no longer be called.
+func releaseRequestBody(req *http.Request) {
+	if r, ok := req.Body.(*pooledBodyReader); ok {
+		r.body.release()
+	}
+}
@@ -0,0 +1,107 @@
+package turnstile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestBuildJSONRequestGetBody(t *testing.T) {
+	client := newVerifierClient(staticSecret("secret"), cloudflareTurnstileUrl, nil)
+
+	httpReq, err := client.buildJSONRequest(context.Background(), cloudflareTurnstileUrl, "secret", &VerificationRequest{Response: "token"})
+	if err != nil {
+		t.Fatalf("buildJSONRequest() error = %v", err)
+	}
+	defer releaseRequestBody(httpReq)
+
+	if httpReq.GetBody == nil {
+		t.Fatal("GetBody is nil, the transport can not replay the request")
+	}
+
+	first, _ := io.ReadAll(httpReq.Body)
+	httpReq.Body.Close()
+
+	replay, err := httpReq.GetBody()
+	if err != nil {
+		t.Fatalf("GetBody() error = %v", err)
+	}
+	defer replay.Close()
+
+	second, _ := io.ReadAll(replay)
+	if !bytes.Equal(first, second) {
+		t.Errorf("replayed body = %q, want %q", second, first)
+	}
+}
+
+func TestPooledRequestBodyConcurrent(t *testing.T) {
+	client := newVerifierClient(staticSecret("secret"), cloudflareTurnstileUrl, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(token string) {
+			defer wg.Done()
+
+			httpReq, err := client.buildJSONRequest(context.Background(), cloudflareTurnstileUrl, "secret", &VerificationRequest{Response: token})
+			if err != nil {
+				t.Errorf("buildJSONRequest() error = %v", err)
+				return
+			}
+			releaseRequestBody(httpReq)
+
+			var payload struct {
+				Response string `json:"response"`
+			}
+			if err := json.NewDecoder(httpReq.Body).Decode(&payload); err != nil {
+				t.Errorf("decoding body: %v", err)
+			}
+			httpReq.Body.Close()
+
+			if payload.Response != token {
+				t.Errorf("body carries response %q, want %q", payload.Response, token)
+			}
+		}(string(rune('a' + i%26)))
+	}
+
+	wg.Wait()
+}
+
+func BenchmarkBuildJSONRequest(b *testing.B) {
+	client := newVerifierClient(staticSecret("secret"), cloudflareTurnstileUrl, nil)
+	req := &VerificationRequest{Response: "token", RemoteIP: "203.0.113.1", IdempotencyKey: "key"}
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			httpReq, err := client.buildJSONRequest(context.Background(), cloudflareTurnstileUrl, "secret", req)
+			if err != nil {
+				b.Fatal(err)
+			}
+			httpReq.Body.Close()
+			releaseRequestBody(httpReq)
+		}
+	})
+
+	// unpooled is how requests were built before buffers were pooled.
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			body := &bytes.Buffer{}
+			if err := json.NewEncoder(body).Encode(siteverifyPayload{VerificationRequest: req, Secret: "secret"}); err != nil {
+				b.Fatal(err)
+			}
+
+			httpReq, err := http.NewRequestWithContext(context.Background(), http.MethodPost, cloudflareTurnstileUrl, body)
+			if err != nil {
+				b.Fatal(err)
+			}
+			httpReq.Header.Set("Content-Type", "application/json")
+			httpReq.Body.Close()
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package turnstile
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const redisCacheKeyPrefix = "turnstile:verification:"
+
+// RedisLike is the subset of a Redis client needed for caching, small enough
+// to wrap go-redis or any other shared store.
+type RedisLike interface {
+	Get(ctx context.Context, key string) (value []byte, found bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+type redisCachingVerifier struct {
+	inner  Verifier
+	client RedisLike
+	ttl    time.Duration
+}
+
+// NewRedisCachingVerifier shares verification results between instances
+// through client. Results are keyed by a hash of the token and kept for ttl.
+// Only Cloudflare's verdicts are cached: successes and rejected tokens, not
+// transport errors or failed expectations. Cache errors fall back to inner.
+//
+// A cached success lets the same token pass again within ttl, so keep ttl
+// short if replays are a concern.
+func NewRedisCachingVerifier(inner Verifier, client RedisLike, ttl time.Duration) Verifier {
+	return &redisCachingVerifier{
+		inner:  inner,
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func (v *redisCachingVerifier) Verify(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+	key := redisCacheKey(req.Response)
+
+	if data, found, err := v.client.Get(ctx, key); err == nil && found {
+		resp := &VerificationResponse{}
+		if err := json.Unmarshal(data, resp); err == nil {
+			if !resp.Success {
+				return resp, mapErrorCodes(resp.ErrorCodes)
+			}
+
+			return resp, nil
+		}
+	}
+
+	resp, err := v.inner.Verify(ctx, req)
+	if isCacheable(resp, err) {
+		if data, err := json.Marshal(resp); err == nil {
+			_ = v.client.Set(ctx, key, data, v.ttl)
+		}
+	}
+
+	return resp, err
+}
+
+func redisCacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return redisCacheKeyPrefix + hex.EncodeToString(sum[:])
+}
+
+func isCacheable(resp *VerificationResponse, err error) bool {
+	if resp == nil {
+		return false
+	}
+
+	if err == nil {
+		return true
+	}
+
+	var verr *VerificationError
+	return !resp.Success && errors.As(err, &verr) && verr.Kind() == OutcomeKindValidationFailure
+}
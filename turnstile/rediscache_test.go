@@ -0,0 +1,116 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeRedis struct {
+	values map[string][]byte
+	ttls   map[string]time.Duration
+	getErr error
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{values: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (r *fakeRedis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if r.getErr != nil {
+		return nil, false, r.getErr
+	}
+
+	value, found := r.values[key]
+	return value, found, nil
+}
+
+func (r *fakeRedis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	r.values[key] = value
+	r.ttls[key] = ttl
+	return nil
+}
+
+func TestRedisCachingVerifier(t *testing.T) {
+	tests := []struct {
+		name      string
+		inner     Verifier
+		wantKind  OutcomeKind
+		wantCalls int
+	}{
+		{name: "success cached", inner: NewOfflineVerifier(TestSecretAlwaysPasses), wantKind: OutcomeKindSuccess, wantCalls: 1},
+		{name: "rejection cached", inner: NewOfflineVerifier(TestSecretTokenSpent), wantKind: OutcomeKindValidationFailure, wantCalls: 1},
+		{name: "invalid request not cached", inner: NewOfflineVerifier("secret"), wantKind: OutcomeKindInvalidRequest, wantCalls: 2},
+		{
+			name: "transport error not cached",
+			inner: verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+				return nil, transportError(errors.New("unreachable"))
+			}),
+			wantKind:  OutcomeKindTransportError,
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			redis := newFakeRedis()
+			v := NewRedisCachingVerifier(verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+				calls++
+				return tt.inner.Verify(ctx, req)
+			}), redis, time.Minute)
+
+			for i := 0; i < 2; i++ {
+				if err := verifyToken(v); KindOf(err) != tt.wantKind {
+					t.Errorf("Verify() #%d error = %v, want kind %v", i+1, err, tt.wantKind)
+				}
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("inner called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRedisCachingVerifierStore(t *testing.T) {
+	redis := newFakeRedis()
+	v := NewRedisCachingVerifier(NewOfflineVerifier(TestSecretTokenSpent), redis, time.Minute)
+
+	verifyToken(v)
+	err := verifyToken(v)
+
+	var verr *VerificationError
+	if !errors.As(err, &verr) || len(verr.ErrorCodes) != 1 || verr.ErrorCodes[0] != CodeTimeoutOrDuplicate {
+		t.Errorf("cached Verify() error = %v, want timeout-or-duplicate", err)
+	}
+
+	if len(redis.values) != 1 {
+		t.Fatalf("store holds %d entries, want 1", len(redis.values))
+	}
+	for key, ttl := range redis.ttls {
+		if strings.Contains(key, "token") || !strings.HasPrefix(key, redisCacheKeyPrefix) {
+			t.Errorf("cache key = %q, want a prefixed hash of the token", key)
+		}
+		if ttl != time.Minute {
+			t.Errorf("cache ttl = %v, want %v", ttl, time.Minute)
+		}
+	}
+}
+
+func TestRedisCachingVerifierStoreError(t *testing.T) {
+	redis := newFakeRedis()
+	redis.getErr = errors.New("connection refused")
+
+	var calls int
+	v := NewRedisCachingVerifier(verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+		calls++
+		return &VerificationResponse{Success: true}, nil
+	}), redis, time.Minute)
+
+	if err := verifyToken(v); err != nil || calls != 1 {
+		t.Errorf("Verify() error = %v with %d inner calls, want nil with 1 call", err, calls)
+	}
+}
@@ -0,0 +1,116 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var errNoSecret = errors.New("no turnstile secret fetched yet")
+
+// ClosingVerifier is a Verifier holding background resources released by
+// Close.
+type ClosingVerifier interface {
+	Verifier
+	Close() error
+}
+
+type refreshingVerifierClient struct {
+	*verifierClient
+	refresher *secretRefresher
+}
+
+// NewVerifierClientWithRefreshingSecret fetches the secret once up front and
+// then every interval in the background, so verifications use the cached
+// value instead of fetching it per call. Failed fetches are logged and the
+// last good secret is kept. Close stops the background refresh. It panics if
+// interval is not positive.
+func NewVerifierClientWithRefreshingSecret(fetch func(ctx context.Context) (string, error), interval time.Duration, opts ...Option) ClosingVerifier {
+	if interval <= 0 {
+		panic(fmt.Sprintf("turnstile: secret refresh interval must be positive, got %s", interval))
+	}
+
+	refresher := newSecretRefresher(fetch, interval)
+
+	return &refreshingVerifierClient{
+		verifierClient: newVerifierClient(refresher.secret, cloudflareTurnstileUrl, opts),
+		refresher:      refresher,
+	}
+}
+
+func (v *refreshingVerifierClient) Close() error {
+	v.refresher.stop()
+	return nil
+}
+
+type secretRefresher struct {
+	fetch   func(ctx context.Context) (string, error)
+	current atomic.Pointer[string]
+
+	cancel   context.CancelFunc
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newSecretRefresher(fetch func(ctx context.Context) (string, error), interval time.Duration) *secretRefresher {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &secretRefresher{
+		fetch:  fetch,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	r.refresh(ctx)
+	go r.run(ctx, interval)
+
+	return r
+}
+
+func (r *secretRefresher) run(ctx context.Context, interval time.Duration) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.refresh(ctx)
+		}
+	}
+}
+
+func (r *secretRefresher) refresh(ctx context.Context) {
+	secret, err := r.fetch(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.WarnContext(ctx, "can not refresh turnstile secret, keeping the previous one", "error", err)
+		}
+
+		return
+	}
+
+	r.current.Store(&secret)
+}
+
+func (r *secretRefresher) secret(context.Context) (string, error) {
+	secret := r.current.Load()
+	if secret == nil {
+		return "", errNoSecret
+	}
+
+	return *secret, nil
+}
+
+func (r *secretRefresher) stop() {
+	r.stopOnce.Do(func() {
+		r.cancel()
+		<-r.done
+	})
+}
@@ -0,0 +1,139 @@
+package turnstile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+)
+
+// secretRecordingClient returns an HTTP client answering every siteverify
+// request in process and storing the secret it was sent in secret.
+func secretRecordingClient(t *testing.T, secret *atomic.Value) *http.Client {
+	return &http.Client{Transport: handlerTransport{handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Decode() error = %v", err)
+		}
+		secret.Store(body["secret"])
+
+		respondJSON(`{"success":true}`)(w, r)
+	})}}
+}
+
+func TestNewVerifierClientWithRefreshingSecret(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var fetches atomic.Int32
+	fetch := func(ctx context.Context) (string, error) {
+		return fmt.Sprintf("secret-%d", fetches.Add(1)), nil
+	}
+
+	var sent atomic.Value
+	v := NewVerifierClientWithRefreshingSecret(fetch, 10*time.Millisecond, WithHTTPClient(secretRecordingClient(t, &sent)))
+	defer v.Close()
+
+	if err := verifyToken(v); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if got := sent.Load(); got != "secret-1" {
+		t.Fatalf("secret = %v, want %q", got, "secret-1")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for sent.Load() == "secret-1" {
+		if time.Now().After(deadline) {
+			t.Fatal("secret not refreshed after the interval")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+		if err := verifyToken(v); err != nil {
+			t.Fatalf("Verify() error = %v", err)
+		}
+	}
+}
+
+func TestRefreshingSecretKeepsLastGoodValue(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var fetches atomic.Int32
+	fetch := func(ctx context.Context) (string, error) {
+		if fetches.Add(1) == 1 {
+			return "good", nil
+		}
+		return "", errors.New("secrets manager unavailable")
+	}
+
+	var sent atomic.Value
+	v := NewVerifierClientWithRefreshingSecret(fetch, time.Millisecond, WithHTTPClient(secretRecordingClient(t, &sent)))
+	defer v.Close()
+
+	for fetches.Load() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := verifyToken(v); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if got := sent.Load(); got != "good" {
+		t.Errorf("secret = %v, want %q", got, "good")
+	}
+}
+
+func TestRefreshingSecretWithoutSecret(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	v := NewVerifierClientWithRefreshingSecret(func(ctx context.Context) (string, error) {
+		return "", errors.New("secrets manager unavailable")
+	}, time.Minute)
+	defer v.Close()
+
+	if err := verifyToken(v); !errors.Is(err, errNoSecret) {
+		t.Errorf("Verify() error = %v, want %v", err, errNoSecret)
+	}
+}
+
+func TestRefreshingSecretCloseStopsRefresh(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var fetches atomic.Int32
+	v := NewVerifierClientWithRefreshingSecret(func(ctx context.Context) (string, error) {
+		fetches.Add(1)
+		return "secret", nil
+	}, time.Millisecond)
+
+	if err := v.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := v.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+
+	stopped := fetches.Load()
+	time.Sleep(10 * time.Millisecond)
+	if got := fetches.Load(); got != stopped {
+		t.Errorf("fetches = %d after Close, want %d", got, stopped)
+	}
+}
+
+func TestRefreshingSecretNonPositiveIntervalPanics(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		t.Run(interval.String(), func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewVerifierClientWithRefreshingSecret() with interval %s did not panic", interval)
+				}
+			}()
+
+			NewVerifierClientWithRefreshingSecret(func(ctx context.Context) (string, error) {
+				return "secret", nil
+			}, interval)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package turnstile
+
+import (
+	"context"
+	"fmt"
+)
+
+type replayVerifier struct {
+	fixtures map[string]VerificationResponse
+}
+
+// NewReplayVerifier returns a Verifier that answers from canned responses keyed
+// by token instead of calling Cloudflare. Unknown tokens fail validation.
+func NewReplayVerifier(fixtures map[string]VerificationResponse) Verifier {
+	return &replayVerifier{fixtures: fixtures}
+}
+
+func (v *replayVerifier) Verify(_ context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+	fixture, ok := v.fixtures[req.Response]
+	if !ok {
+		return nil, fmt.Errorf("no fixture recorded for response: %w", ErrValidationFailed)
+	}
+
+	resp := fixture
+	if !resp.Success {
+		return &resp, mapErrorCodes(resp.ErrorCodes)
+	}
+
+	return &resp, nil
+}
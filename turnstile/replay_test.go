@@ -0,0 +1,46 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestReplayVerifier(t *testing.T) {
+	v := NewReplayVerifier(map[string]VerificationResponse{
+		"good":  {Success: true, Hostname: "example.com", Action: "login"},
+		"spent": {ErrorCodes: []ErrorCode{CodeTimeoutOrDuplicate}},
+	})
+
+	resp, err := v.Verify(context.Background(), &VerificationRequest{Response: "good"})
+	if err != nil {
+		t.Fatalf("Verify(good) error = %v", err)
+	}
+	if resp.Hostname != "example.com" || resp.Action != "login" {
+		t.Errorf("Verify(good) = %+v, want the recorded fixture", resp)
+	}
+
+	resp, err = v.Verify(context.Background(), &VerificationRequest{Response: "spent"})
+	if !errors.Is(err, ErrValidationFailed) {
+		t.Errorf("Verify(spent) error = %v, want ErrValidationFailed", err)
+	}
+	if resp == nil || len(resp.ErrorCodes) != 1 || resp.ErrorCodes[0] != CodeTimeoutOrDuplicate {
+		t.Errorf("Verify(spent) = %+v, want the recorded error codes", resp)
+	}
+
+	if _, err := v.Verify(context.Background(), &VerificationRequest{Response: "unknown"}); !errors.Is(err, ErrValidationFailed) {
+		t.Errorf("Verify(unknown) error = %v, want ErrValidationFailed", err)
+	}
+}
+
+func TestReplayVerifierReturnsCopies(t *testing.T) {
+	v := NewReplayVerifier(map[string]VerificationResponse{"good": {Success: true, Hostname: "example.com"}})
+
+	resp, _ := v.Verify(context.Background(), &VerificationRequest{Response: "good"})
+	resp.Hostname = "changed"
+
+	resp, _ = v.Verify(context.Background(), &VerificationRequest{Response: "good"})
+	if resp.Hostname != "example.com" {
+		t.Errorf("Hostname = %q after mutating an earlier result, want example.com", resp.Hostname)
+	}
+}
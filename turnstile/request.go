@@ -0,0 +1,67 @@
+package turnstile
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// RequestBuilder builds the HTTP request sent to the verification endpoint at
+// url for req, authenticated with secret. It is called once per attempt.
+type RequestBuilder func(ctx context.Context, url, secret string, req *VerificationRequest) (*http.Request, error)
+
+type siteverifyPayload struct {
+	*VerificationRequest
+	Secret string `json:"secret"`
+}
+
+// buildJSONRequest is the default RequestBuilder, POSTing a JSON body as
+// Cloudflare's siteverify expects.
+func (t *verifierClient) buildJSONRequest(ctx context.Context, url, secret string, req *VerificationRequest) (*http.Request, error) {
+	reqBody := newPooledRequestBody()
+	if err := t.jsonCodec.Encode(&reqBody.buf, siteverifyPayload{VerificationRequest: req, Secret: secret}); err != nil {
+		reqBody.release()
+		return nil, fmt.Errorf("can not marshall verification request to JSON: %w", err)
+	}
+
+	bodyReader := reqBody.newReader()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bodyReader)
+	if err != nil {
+		bodyReader.Close()
+		reqBody.release()
+		return nil, err
+	}
+
+	// The request keeps its reference until post releases it, so the
+	// transport can replay the body, e.g. on a stale keep-alive connection or
+	// a 307 redirect.
+	httpReq.ContentLength = int64(bodyReader.Len())
+	httpReq.GetBody = reqBody.getBody
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	return httpReq, nil
+}
+
+// QueryRequestBuilder sends the verification as a GET request with the fields
+// as query parameters, for compatible verification proxies that expect them.
+// The secret ends up in the URL, so the proxy must not log request URLs.
+func QueryRequestBuilder(ctx context.Context, endpoint, secret string, req *VerificationRequest) (*http.Request, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	query := u.Query()
+	query.Set("secret", secret)
+	query.Set("response", req.Response)
+	if req.RemoteIP != "" {
+		query.Set("remoteip", req.RemoteIP)
+	}
+	if req.IdempotencyKey != "" {
+		query.Set("idempotency_key", req.IdempotencyKey)
+	}
+	u.RawQuery = query.Encode()
+
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
@@ -0,0 +1,60 @@
+package turnstile
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestQueryRequestBuilder(t *testing.T) {
+	var method string
+	var query url.Values
+	srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		query = r.URL.Query()
+		respondJSON(`{"success":true}`)(w, r)
+	})
+
+	v := NewVerifierClientWithURL("secret", srv.URL+"/verify?tenant=a", WithRequestBuilder(QueryRequestBuilder))
+
+	_, err := v.Verify(context.Background(), &VerificationRequest{
+		Response:       "token",
+		RemoteIP:       "203.0.113.1",
+		IdempotencyKey: "key",
+	})
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	if method != http.MethodGet {
+		t.Errorf("method = %s, want %s", method, http.MethodGet)
+	}
+
+	want := url.Values{
+		"tenant":          {"a"},
+		"secret":          {"secret"},
+		"response":        {"token"},
+		"remoteip":        {"203.0.113.1"},
+		"idempotency_key": {"key"},
+	}
+	for name := range want {
+		if query.Get(name) != want.Get(name) {
+			t.Errorf("query parameter %s = %q, want %q", name, query.Get(name), want.Get(name))
+		}
+	}
+}
+
+func TestQueryRequestBuilderOmitsEmptyFields(t *testing.T) {
+	req, err := QueryRequestBuilder(context.Background(), "https://example.com/verify", "secret", &VerificationRequest{Response: "token"})
+	if err != nil {
+		t.Fatalf("QueryRequestBuilder() error = %v", err)
+	}
+
+	query := req.URL.Query()
+	for _, name := range []string{"remoteip", "idempotency_key"} {
+		if query.Has(name) {
+			t.Errorf("query parameter %s = %q sent, want none", name, query.Get(name))
+		}
+	}
+}
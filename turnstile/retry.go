@@ -0,0 +1,79 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTPStatusError is returned, wrapped in a VerificationError, when siteverify
+// answers with a non-2xx status.
+type HTTPStatusError struct {
+	StatusCode int
+	// RetryAfter is the delay requested by a Retry-After header, or zero.
+	RetryAfter time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %d from turnstile", e.StatusCode)
+}
+
+func isRetryable(err error) bool {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+
+	return IsTransient(err)
+}
+
+func (t *verifierClient) retryDelay(attempt int, err error) time.Duration {
+	var delay time.Duration
+
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		delay = statusErr.RetryAfter
+	} else {
+		backoff := t.backoff
+		if backoff == nil {
+			backoff = ExponentialBackoff(t.retryBaseDelay, t.retryMaxDelay)
+		}
+
+		delay = backoff.Next(attempt)
+	}
+
+	return min(delay, t.retryMaxDelay)
+}
+
+// parseRetryAfter parses a Retry-After header holding either a number of
+// seconds or an HTTP date. Invalid or past values yield zero.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if secs, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(secs)*time.Second, 0)
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+
+	return 0
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
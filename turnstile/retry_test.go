@@ -0,0 +1,172 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 0},
+		{value: "3", want: 3 * time.Second},
+		{value: "0", want: 0},
+		{value: "-1", want: 0},
+		{value: now.Add(5 * time.Second).Format(http.TimeFormat), want: 5 * time.Second},
+		{value: now.Add(-5 * time.Second).Format(http.TimeFormat), want: 0},
+		{value: "soon", want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	v := NewVerifierClient("secret", WithRetry(3, 100*time.Millisecond, 5*time.Second)).(*verifierClient)
+
+	tests := []struct {
+		name    string
+		attempt int
+		err     error
+		want    time.Duration
+	}{
+		{
+			name:    "retry after",
+			attempt: 1,
+			err:     transportError(&HTTPStatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 3 * time.Second}),
+			want:    3 * time.Second,
+		},
+		{
+			name:    "retry after capped",
+			attempt: 1,
+			err:     transportError(&HTTPStatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute}),
+			want:    5 * time.Second,
+		},
+		{
+			name:    "backoff without retry after",
+			attempt: 2,
+			err:     transportError(&HTTPStatusError{StatusCode: http.StatusServiceUnavailable}),
+			want:    200 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := v.retryDelay(tt.attempt, tt.err); got != tt.want {
+				t.Errorf("retryDelay(%d, %v) = %v, want %v", tt.attempt, tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRetryAfterHeader(t *testing.T) {
+	srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	v := NewVerifierClientWithURL("secret", srv.URL)
+
+	var statusErr *HTTPStatusError
+	if err := verifyToken(v); !errors.As(err, &statusErr) {
+		t.Fatalf("Verify() error = %v, want *HTTPStatusError", err)
+	}
+	if statusErr.StatusCode != http.StatusTooManyRequests || statusErr.RetryAfter != 7*time.Second {
+		t.Errorf("HTTPStatusError = %+v, want status 429 and RetryAfter 7s", statusErr)
+	}
+}
+
+func TestVerifyTimeoutDuringRetryDelay(t *testing.T) {
+	var calls int
+	srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	v := NewVerifierClientWithURL("secret", srv.URL, WithRetry(3, time.Millisecond, time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := v.Verify(ctx, &VerificationRequest{Response: "token"})
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Verify() took %v, want it to stop at the context deadline", elapsed)
+	}
+	if calls != 1 {
+		t.Errorf("siteverify called %d times, want 1", calls)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Verify() error = %v, want it to wrap context.DeadlineExceeded", err)
+	}
+
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		t.Errorf("Verify() error = %v, want it to keep the last *HTTPStatusError", err)
+	}
+	if got := KindOf(err); got != OutcomeKindTransportError {
+		t.Errorf("KindOf(%v) = %v, want %v", err, got, OutcomeKindTransportError)
+	}
+}
+
+func TestWithTotalTimeoutDuringBackoff(t *testing.T) {
+	var calls int
+	srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	v := NewVerifierClientWithURL("secret", srv.URL,
+		WithRetry(3, time.Minute, time.Minute),
+		WithTotalTimeout(50*time.Millisecond),
+	)
+
+	start := time.Now()
+	err := verifyToken(v)
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Verify() took %v, want it to stop at the total timeout", elapsed)
+	}
+	if calls != 1 {
+		t.Errorf("siteverify called %d times, want 1", calls)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Verify() error = %v, want it to wrap context.DeadlineExceeded", err)
+	}
+}
+
+func TestWithTotalTimeoutNotReached(t *testing.T) {
+	var calls int
+	srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		respondJSON(`{"success":true}`)(w, r)
+	})
+
+	v := NewVerifierClientWithURL("secret", srv.URL,
+		WithRetry(3, time.Millisecond, time.Millisecond),
+		WithTotalTimeout(time.Minute),
+	)
+
+	if err := verifyToken(v); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("siteverify called %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,73 @@
+package turnstile
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+type samplingLogVerifier struct {
+	inner  Verifier
+	rate   float64
+	logger *slog.Logger
+	calls  atomic.Uint64
+}
+
+// NewSamplingLogVerifier logs the details of roughly rate (0 to 1) of all
+// calls to inner. Sampling is deterministic: every call advances a counter and
+// a call is logged whenever the counter crosses the next multiple of 1/rate.
+// The secret is never logged and the token only in masked form.
+func NewSamplingLogVerifier(inner Verifier, rate float64, logger *slog.Logger) Verifier {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &samplingLogVerifier{
+		inner:  inner,
+		rate:   min(max(rate, 0), 1),
+		logger: logger,
+	}
+}
+
+func (v *samplingLogVerifier) Verify(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+	if !v.sample() {
+		return v.inner.Verify(ctx, req)
+	}
+
+	start := time.Now()
+	resp, err := v.inner.Verify(ctx, req)
+
+	attrs := []slog.Attr{
+		slog.String("remote_ip", req.RemoteIP),
+		slog.String("idempotency_key", req.IdempotencyKey),
+		slog.String("token", maskToken(req.Response)),
+		slog.Int("token_length", len(req.Response)),
+		slog.Duration("duration", time.Since(start)),
+		slog.String("outcome", KindOf(err).String()),
+	}
+
+	if resp != nil {
+		attrs = append(attrs,
+			slog.Bool("success", resp.Success),
+			slog.Time("challenge_ts", resp.ChallengeTs),
+			slog.String("hostname", resp.Hostname),
+			slog.String("action", resp.Action),
+			slog.String("error_codes", formatErrorCodes(resp.ErrorCodes)),
+		)
+	}
+
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	v.logger.LogAttrs(ctx, slog.LevelInfo, "turnstile verification trace", attrs...)
+
+	return resp, err
+}
+
+func (v *samplingLogVerifier) sample() bool {
+	n := float64(v.calls.Add(1))
+	return math.Floor(n*v.rate) > math.Floor((n-1)*v.rate)
+}
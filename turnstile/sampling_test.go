@@ -0,0 +1,56 @@
+package turnstile
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSamplingLogVerifier(t *testing.T) {
+	const calls = 10
+
+	tests := []struct {
+		rate float64
+		want int
+	}{
+		{rate: 1, want: calls},
+		{rate: 0.5, want: calls / 2},
+		{rate: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		v := NewSamplingLogVerifier(NewOfflineVerifier(TestSecretAlwaysPasses), tt.rate, slog.New(slog.NewJSONHandler(&buf, nil)))
+
+		for i := 0; i < calls; i++ {
+			if err := verifyToken(v); err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+		}
+
+		if got := strings.Count(buf.String(), "turnstile verification trace"); got != tt.want {
+			t.Errorf("rate %v logged %d traces, want %d", tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestSamplingLogVerifierOmitsSecrets(t *testing.T) {
+	const token = "0.abcdefghijklmnopqrstuvwxyz0123456789"
+
+	var buf bytes.Buffer
+	v := NewSamplingLogVerifier(NewOfflineVerifier(TestSecretAlwaysFails), 1, slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	if _, err := v.Verify(context.Background(), &VerificationRequest{Response: token}); err == nil {
+		t.Fatal("Verify() error = nil, want a validation failure")
+	}
+
+	out := buf.String()
+	if strings.Contains(out, token) || strings.Contains(out, TestSecretAlwaysFails) {
+		t.Errorf("trace %s contains the token or the secret", out)
+	}
+	if !strings.Contains(out, maskToken(token)) || !strings.Contains(out, string(CodeInvalidInputResponse)) {
+		t.Errorf("trace %s lacks the masked token or the error codes", out)
+	}
+}
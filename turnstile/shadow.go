@@ -0,0 +1,120 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+const (
+	// shadowTimeout bounds shadow calls of requests without a deadline.
+	shadowTimeout = 10 * time.Second
+	// shadowMaxInFlight is the number of concurrent shadow calls beyond which
+	// further ones are dropped.
+	shadowMaxInFlight = 64
+)
+
+// Outcome is the result of a single call to a Verifier.
+type Outcome struct {
+	Response *VerificationResponse
+	Err      error
+}
+
+func (o Outcome) Kind() OutcomeKind {
+	return KindOf(o.Err)
+}
+
+// errorCodes returns the sorted error codes of o, from its error or else its
+// response.
+func (o Outcome) errorCodes() []ErrorCode {
+	var codes []ErrorCode
+	var verr *VerificationError
+	if errors.As(o.Err, &verr) {
+		codes = verr.ErrorCodes
+	} else if o.Response != nil {
+		codes = o.Response.ErrorCodes
+	}
+
+	codes = slices.Clone(codes)
+	slices.Sort(codes)
+
+	return codes
+}
+
+// outcomesDiffer reports whether a and b differ in kind or error codes, or,
+// when both succeeded, in hostname or action.
+func outcomesDiffer(a, b Outcome) bool {
+	if a.Kind() != b.Kind() || !slices.Equal(a.errorCodes(), b.errorCodes()) {
+		return true
+	}
+
+	if a.Err != nil || a.Response == nil || b.Response == nil {
+		return false
+	}
+
+	return a.Response.Hostname != b.Response.Hostname || a.Response.Action != b.Response.Action
+}
+
+type shadowVerifier struct {
+	primary Verifier
+	shadow  Verifier
+	onDiff  func(primary, shadow Outcome)
+	sem     chan struct{}
+}
+
+// NewShadowVerifier returns a Verifier that answers with primary while also
+// sending every request to shadow, e.g. to compare a new provider or gateway
+// before migrating. The shadow call runs in its own goroutine and never
+// affects the returned result or its latency; onDiff is called from that
+// goroutine whenever the two outcomes differ in kind or error codes, or, when
+// both succeeded, in hostname or action. Shadow calls
+// outlive cancellation of the request but keep its deadline, or are bounded
+// to ten seconds without one, and are dropped while 64 of them are in flight.
+func NewShadowVerifier(primary, shadow Verifier, onDiff func(primary, shadow Outcome)) Verifier {
+	return &shadowVerifier{
+		primary: primary,
+		shadow:  shadow,
+		onDiff:  onDiff,
+		sem:     make(chan struct{}, shadowMaxInFlight),
+	}
+}
+
+func (v *shadowVerifier) Verify(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+	select {
+	case v.sem <- struct{}{}:
+	default:
+		return v.primary.Verify(ctx, req)
+	}
+
+	primaryOutcome := make(chan Outcome, 1)
+	shadowReq := *req
+	shadowCtx, cancel := shadowContext(ctx)
+
+	go func() {
+		defer func() { <-v.sem }()
+		defer cancel()
+
+		resp, err := v.shadow.Verify(shadowCtx, &shadowReq)
+		shadowResult := Outcome{Response: resp, Err: err}
+
+		primaryResult := <-primaryOutcome
+		if v.onDiff != nil && outcomesDiffer(primaryResult, shadowResult) {
+			v.onDiff(primaryResult, shadowResult)
+		}
+	}()
+
+	resp, err := v.primary.Verify(ctx, req)
+	primaryOutcome <- Outcome{Response: resp, Err: err}
+
+	return resp, err
+}
+
+func shadowContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+
+	return context.WithTimeout(detached, shadowTimeout)
+}
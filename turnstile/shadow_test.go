@@ -0,0 +1,119 @@
+package turnstile
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// waitShadowCalls waits until v has no shadow call in flight.
+func waitShadowCalls(t *testing.T, v Verifier) {
+	t.Helper()
+
+	sv := v.(*shadowVerifier)
+	deadline := time.Now().Add(time.Second)
+	for len(sv.sem) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("shadow call did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestShadowVerifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		primary  string
+		shadow   string
+		wantDiff bool
+	}{
+		{name: "both pass", primary: TestSecretAlwaysPasses, shadow: TestSecretAlwaysPasses},
+		{name: "both fail", primary: TestSecretAlwaysFails, shadow: TestSecretAlwaysFails},
+		{name: "different error codes", primary: TestSecretAlwaysFails, shadow: TestSecretTokenSpent, wantDiff: true},
+		{name: "shadow fails", primary: TestSecretAlwaysPasses, shadow: TestSecretAlwaysFails, wantDiff: true},
+		{name: "shadow passes", primary: TestSecretAlwaysFails, shadow: TestSecretAlwaysPasses, wantDiff: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs := make(chan [2]Outcome, 1)
+			v := NewShadowVerifier(NewOfflineVerifier(tt.primary), NewOfflineVerifier(tt.shadow), func(primary, shadow Outcome) {
+				diffs <- [2]Outcome{primary, shadow}
+			})
+
+			_, err := v.Verify(context.Background(), &VerificationRequest{Response: "token"})
+			if got, want := KindOf(err), KindOf(verifyToken(NewOfflineVerifier(tt.primary))); got != want {
+				t.Errorf("Verify() kind = %v, want primary kind %v", got, want)
+			}
+
+			waitShadowCalls(t, v)
+
+			select {
+			case diff := <-diffs:
+				if !tt.wantDiff {
+					t.Errorf("onDiff(%v, %v) called, want no call", diff[0].Kind(), diff[1].Kind())
+				}
+			default:
+				if tt.wantDiff {
+					t.Error("onDiff not called, want a call")
+				}
+			}
+		})
+	}
+}
+
+func TestOutcomesDiffer(t *testing.T) {
+	pass := func(hostname, action string) Outcome {
+		return Outcome{Response: &VerificationResponse{Success: true, Hostname: hostname, Action: action}}
+	}
+	fail := func(codes ...ErrorCode) Outcome {
+		return Outcome{Response: &VerificationResponse{ErrorCodes: codes}, Err: mapErrorCodes(codes)}
+	}
+
+	tests := []struct {
+		name string
+		a, b Outcome
+		want bool
+	}{
+		{name: "same success", a: pass("example.com", "login"), b: pass("example.com", "login")},
+		{name: "different hostname", a: pass("example.com", "login"), b: pass("evil.example", "login"), want: true},
+		{name: "different action", a: pass("example.com", "login"), b: pass("example.com", "signup"), want: true},
+		{name: "same codes", a: fail(CodeInvalidInputResponse), b: fail(CodeInvalidInputResponse)},
+		{name: "codes in another order", a: fail(CodeInvalidInputResponse, CodeBadRequest), b: fail(CodeBadRequest, CodeInvalidInputResponse)},
+		{name: "different codes", a: fail(CodeInvalidInputResponse), b: fail(CodeTimeoutOrDuplicate), want: true},
+		{name: "different kind", a: pass("example.com", "login"), b: fail(CodeInvalidInputResponse), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := outcomesDiffer(tt.a, tt.b); got != tt.want {
+				t.Errorf("outcomesDiffer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShadowVerifierOutlivesCancellation(t *testing.T) {
+	release := make(chan struct{})
+	shadowErr := make(chan error, 1)
+	shadow := verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+		<-release
+		shadowErr <- ctx.Err()
+		return &VerificationResponse{Success: true}, nil
+	})
+
+	v := NewShadowVerifier(NewOfflineVerifier(TestSecretAlwaysPasses), shadow, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := v.Verify(ctx, &VerificationRequest{Response: "token"}); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	cancel()
+	close(release)
+
+	if err := <-shadowErr; err != nil {
+		t.Errorf("shadow context error = %v, want nil", err)
+	}
+	waitShadowCalls(t, v)
+}
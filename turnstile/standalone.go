@@ -0,0 +1,23 @@
+package turnstile
+
+import (
+	"context"
+	"sync"
+)
+
+var standaloneClients sync.Map
+
+// Verify checks a single token without setting up a Verifier, which is handy
+// for workers and CLI tools. The client for each secret is created once and
+// reused by subsequent calls.
+func Verify(ctx context.Context, secret, token, remoteIP string) (*VerificationResponse, error) {
+	client, ok := standaloneClients.Load(secret)
+	if !ok {
+		client, _ = standaloneClients.LoadOrStore(secret, NewVerifierClient(secret))
+	}
+
+	return client.(Verifier).Verify(ctx, &VerificationRequest{
+		Response: token,
+		RemoteIP: remoteIP,
+	})
+}
@@ -0,0 +1,67 @@
+package turnstile
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// routeDefaultClients makes clients built on http.DefaultTransport talk to srv
+// for the rest of the test.
+func routeDefaultClients(t *testing.T, srvURL string) {
+	target, err := url.Parse(srvURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original := http.DefaultTransport
+	http.DefaultTransport = redirectTransport{target: target, next: original}
+	t.Cleanup(func() { http.DefaultTransport = original })
+}
+
+func TestStandaloneVerify(t *testing.T) {
+	var received map[string]string
+	srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Write([]byte(`{"success":true,"hostname":"example.com"}`))
+	})
+
+	routeDefaultClients(t, srv.URL)
+
+	resp, err := Verify(context.Background(), "standalone-secret", "token", "203.0.113.1")
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	if !resp.Success || resp.Hostname != "example.com" {
+		t.Errorf("Verify() = %+v, want success for example.com", resp)
+	}
+
+	want := map[string]string{"secret": "standalone-secret", "response": "token", "remoteip": "203.0.113.1"}
+	for field, value := range want {
+		if received[field] != value {
+			t.Errorf("siteverify received %s = %q, want %q", field, received[field], value)
+		}
+	}
+}
+
+func TestStandaloneVerifyCachesClient(t *testing.T) {
+	srv := newSiteverifyServer(t, respondJSON(`{"success":true}`))
+	routeDefaultClients(t, srv.URL)
+
+	if _, err := Verify(context.Background(), "cached-secret", "token", ""); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	first, _ := standaloneClients.Load("cached-secret")
+	if _, err := Verify(context.Background(), "cached-secret", "token", ""); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	second, _ := standaloneClients.Load("cached-secret")
+
+	if first == nil || first != second {
+		t.Error("client for the same secret was not reused")
+	}
+}
@@ -0,0 +1,22 @@
+package turnstile
+
+import "sync/atomic"
+
+// VerifyStats counts the calls made by a verifier client. Comparing Attempts
+// to Verifications shows how often retries happen. It is safe for concurrent
+// use.
+type VerifyStats struct {
+	verifications atomic.Int64
+	attempts      atomic.Int64
+}
+
+// Verifications returns the number of calls to Verify.
+func (s *VerifyStats) Verifications() int64 {
+	return s.verifications.Load()
+}
+
+// Attempts returns the number of HTTP requests sent to siteverify, including
+// retries.
+func (s *VerifyStats) Attempts() int64 {
+	return s.attempts.Load()
+}
@@ -0,0 +1,36 @@
+package turnstile
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVerifyStatsCountsAttempts(t *testing.T) {
+	var calls atomic.Int32
+	srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		respondJSON(`{"success":true}`)(w, r)
+	})
+
+	var stats VerifyStats
+	v := NewVerifierClientWithURL("secret", srv.URL,
+		WithRetry(3, time.Millisecond, time.Millisecond),
+		WithVerifyStats(&stats),
+	)
+
+	if err := verifyToken(v); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	if got := stats.Attempts(); got != 3 {
+		t.Errorf("Attempts() = %d, want 3", got)
+	}
+	if got := stats.Verifications(); got != 1 {
+		t.Errorf("Verifications() = %d, want 1", got)
+	}
+}
@@ -0,0 +1,106 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("turnstile circuit open")
+
+// successRateMinSamples is the number of attempts needed within the window
+// before the success rate is trusted to open the circuit.
+const successRateMinSamples = 10
+
+// successRateBuckets is the number of buckets the window is divided into.
+// Outcomes age out of the window one bucket at a time.
+const successRateBuckets = 10
+
+// successRateBucket counts the outcomes of one slice of the window, identified
+// by its epoch, the number of bucket widths since the Unix epoch.
+type successRateBucket struct {
+	epoch     int64
+	successes int
+	failures  int
+}
+
+type successRateVerifier struct {
+	inner   Verifier
+	width   time.Duration
+	minRate float64
+
+	mu      sync.Mutex
+	buckets [successRateBuckets]successRateBucket
+}
+
+// NewSuccessRateVerifier fails fast with ErrCircuitOpen, classified as a
+// transport error, while the success rate of calls to inner over the rolling
+// window is below minRate. Only transport level failures and Cloudflare
+// internal errors count as failures; rejected tokens prove the service is up
+// and count as successes. Outcomes are counted per tenth of the window and age
+// out a tenth at a time, so the cost of a call and the memory used do not grow
+// with traffic. The circuit closes again once failures age out of the window.
+func NewSuccessRateVerifier(inner Verifier, window time.Duration, minRate float64) Verifier {
+	return &successRateVerifier{
+		inner:   inner,
+		width:   max(window/successRateBuckets, 1),
+		minRate: minRate,
+	}
+}
+
+func (v *successRateVerifier) Verify(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+	if v.open(time.Now()) {
+		return nil, transportError(ErrCircuitOpen)
+	}
+
+	resp, err := v.inner.Verify(ctx, req)
+	if ctx.Err() == nil {
+		v.record(time.Now(), !IsTransient(err))
+	}
+
+	return resp, err
+}
+
+func (v *successRateVerifier) open(now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	epoch := v.epoch(now)
+
+	var successes, total int
+	for _, b := range v.buckets {
+		if b.epoch > epoch-successRateBuckets {
+			successes += b.successes
+			total += b.successes + b.failures
+		}
+	}
+
+	if total < successRateMinSamples {
+		return false
+	}
+
+	return float64(successes)/float64(total) < v.minRate
+}
+
+func (v *successRateVerifier) record(now time.Time, ok bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	epoch := v.epoch(now)
+
+	b := &v.buckets[epoch%successRateBuckets]
+	if b.epoch != epoch {
+		*b = successRateBucket{epoch: epoch}
+	}
+
+	if ok {
+		b.successes++
+	} else {
+		b.failures++
+	}
+}
+
+func (v *successRateVerifier) epoch(now time.Time) int64 {
+	return now.UnixNano() / int64(v.width)
+}
@@ -0,0 +1,105 @@
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSuccessRateVerifier(t *testing.T) {
+	errDown := transportError(errors.New("siteverify down"))
+	errRejected := mapErrorCodes([]ErrorCode{CodeInvalidInputResponse})
+
+	tests := []struct {
+		name     string
+		ok       int
+		down     int
+		rejected int
+		wantOpen bool
+	}{
+		{name: "above threshold", ok: 6, down: 4},
+		{name: "below threshold", ok: 4, down: 6, wantOpen: true},
+		{name: "validation failures", down: 4, rejected: 6},
+		{name: "too few samples", down: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var results []error
+			for i := 0; i < tt.ok; i++ {
+				results = append(results, nil)
+			}
+			for i := 0; i < tt.down; i++ {
+				results = append(results, errDown)
+			}
+			for i := 0; i < tt.rejected; i++ {
+				results = append(results, errRejected)
+			}
+
+			var calls int
+			v := NewSuccessRateVerifier(verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+				defer func() { calls++ }()
+				if calls < len(results) {
+					return nil, results[calls]
+				}
+				return &VerificationResponse{Success: true}, nil
+			}), time.Minute, 0.5)
+
+			for range results {
+				verifyToken(v)
+			}
+
+			err := verifyToken(v)
+			if open := errors.Is(err, ErrCircuitOpen); open != tt.wantOpen {
+				t.Fatalf("Verify() error = %v, want circuit open %v", err, tt.wantOpen)
+			}
+			if tt.wantOpen {
+				if !IsTransient(err) {
+					t.Errorf("IsTransient(%v) = false, want true", err)
+				}
+				if calls != len(results) {
+					t.Errorf("inner called %d times, want %d", calls, len(results))
+				}
+			}
+		})
+	}
+}
+
+func TestSuccessRateVerifierCloses(t *testing.T) {
+	const window = 20 * time.Millisecond
+
+	fail := true
+	v := NewSuccessRateVerifier(verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+		if fail {
+			return nil, transportError(errors.New("siteverify down"))
+		}
+		return &VerificationResponse{Success: true}, nil
+	}), window, 0.5)
+
+	for i := 0; i < successRateMinSamples; i++ {
+		verifyToken(v)
+	}
+	if err := verifyToken(v); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Verify() error = %v, want ErrCircuitOpen", err)
+	}
+
+	fail = false
+	time.Sleep(2 * window)
+
+	if err := verifyToken(v); err != nil {
+		t.Errorf("Verify() after the window error = %v, want nil", err)
+	}
+}
+
+func BenchmarkSuccessRateVerifier(b *testing.B) {
+	v := NewSuccessRateVerifier(NewOfflineVerifier(TestSecretAlwaysPasses), time.Minute, 0.5)
+	req := &VerificationRequest{Response: "token"}
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			v.Verify(context.Background(), req)
+		}
+	})
+}
@@ -3,128 +3,403 @@ package turnstile
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
-	"slices"
 	"time"
 )
 
-const cloudflareTurnstileUrl = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+const (
+	cloudflareTurnstileUrl = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+	defaultAcceptHeader    = "application/json"
+	maxResponseBytes       = 1 << 20
 
-type turnstileErrorCode string
+	// DefaultMaxTokenLength is the longest token accepted by default, the
+	// maximum length Cloudflare documents for Turnstile tokens.
+	DefaultMaxTokenLength = 2048
 
-var (
-	missingInputSecret   = turnstileErrorCode("missing-input-secret")
-	invalidInputSecret   = turnstileErrorCode("invalid-input-secret")
-	missingInputResponse = turnstileErrorCode("missing-input-response")
-	invalidInputResponse = turnstileErrorCode("invalid-input-response")
-	invalidWidgetID      = turnstileErrorCode("invalid-widget-id")
-	invalidParsedSecret  = turnstileErrorCode("invalid-parsed-secret")
-	badRequest           = turnstileErrorCode("bad-request")
-	timeoutOrDuplicate   = turnstileErrorCode("timeout-or-duplicate")
-	internalError        = turnstileErrorCode("internal-error")
-)
-
-var (
-	ErrInvalidRequest   = errors.New("invalid request")
-	ErrValidationFailed = errors.New("response validation failed")
+	// DefaultTokenTTL is how long Cloudflare considers a token valid after it
+	// was issued.
+	DefaultTokenTTL = 300 * time.Second
 )
 
 type VerificationRequest struct {
 	Response       string `json:"response"`
-	RemoteIP       string `json:"remoteip"`
-	IdempotencyKey string `json:"idempotency_key"`
+	RemoteIP       string `json:"remoteip,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// IssuedAt is when the widget issued the token, if known to the client.
+	// It is not sent to Cloudflare; see WithTokenTTL.
+	IssuedAt time.Time `json:"-"`
 }
 
 type VerificationResponse struct {
-	Success     bool                 `json:"success"`
-	ChallengeTs time.Time            `json:"challenge_ts"`
-	Hostname    string               `json:"hostname"`
-	ErrorCodes  []turnstileErrorCode `json:"error-codes"`
-	Action      string               `json:"action"`
-	Cdata       string               `json:"cdata"`
+	Success     bool        `json:"success"`
+	ChallengeTs time.Time   `json:"challenge_ts"`
+	Hostname    string      `json:"hostname"`
+	ErrorCodes  []ErrorCode `json:"error-codes"`
+	Action      string      `json:"action"`
+	Cdata       string      `json:"cdata"`
+}
+
+// UnmarshalJSON decodes a siteverify response, parsing challenge_ts leniently:
+// RFC 3339 first, then epoch seconds. An unparseable timestamp leaves
+// ChallengeTs zero instead of failing the whole response.
+func (r *VerificationResponse) UnmarshalJSON(data []byte) error {
+	type verificationResponse VerificationResponse
+	aux := struct {
+		*verificationResponse
+		ChallengeTs json.RawMessage `json:"challenge_ts"`
+	}{
+		verificationResponse: (*verificationResponse)(r),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	r.ChallengeTs = parseChallengeTs(aux.ChallengeTs)
+	return nil
+}
+
+func parseChallengeTs(raw json.RawMessage) time.Time {
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		if ts, err := time.Parse(time.RFC3339Nano, str); err == nil {
+			return ts
+		}
+
+		raw = json.RawMessage(str)
+	}
+
+	var epoch json.Number
+	if err := json.Unmarshal(raw, &epoch); err == nil {
+		if secs, err := epoch.Float64(); err == nil {
+			return time.Unix(0, int64(secs*float64(time.Second))).UTC()
+		}
+	}
+
+	return time.Time{}
 }
 
 type Verifier interface {
 	Verify(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error)
 }
 
+// TokenVerifier is a reduced Verifier for callers that only need to know
+// whether a token is valid.
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, token string) error
+}
+
+type tokenVerifier struct {
+	verifier Verifier
+}
+
+// NewTokenVerifier adapts a Verifier to a TokenVerifier, returning nil on
+// success and the verifier's error otherwise.
+func NewTokenVerifier(verifier Verifier) TokenVerifier {
+	return &tokenVerifier{verifier: verifier}
+}
+
+func (v *tokenVerifier) VerifyToken(ctx context.Context, token string) error {
+	_, err := v.verifier.Verify(ctx, &VerificationRequest{Response: token})
+	return err
+}
+
 type verifierClient struct {
-	secret string
-	url    string
+	secretFunc         func(ctx context.Context) (string, error)
+	url                string
+	httpClient         *http.Client
+	customClient       *http.Client
+	tlsConfig          *tls.Config
+	insecureSkipVerify bool
+	dialContext        func(ctx context.Context, network, addr string) (net.Conn, error)
+	acceptHeader       string
+	requireRemoteIP    bool
+	maxAttempts        int
+	retryBaseDelay     time.Duration
+	retryMaxDelay      time.Duration
+	backoff            Backoff
+	rawResponseSink    func([]byte)
+
+	deriveIdempotencyKey bool
+	maxTokenLength       int
+	batchConcurrency     int
+
+	allowedActions     []string
+	allowedHostnames   []string
+	hostnamePatternErr error
+	hostnameValidator  func(ctx context.Context, hostname string) error
+	maxChallengeAge    time.Duration
+	tokenTTL           time.Duration
+	strictMode         bool
+
+	stats     *VerifyStats
+	jsonCodec JSONCodec
+	eventSink func(ctx context.Context, e VerifyEvent)
+
+	requestBuilder  RequestBuilder
+	tracePropagator func(ctx context.Context, header http.Header)
+
+	collapseErrors bool
+
+	totalTimeout      time.Duration
+	perRequestTimeout time.Duration
 }
 
-func NewVerifierClient(secret string) Verifier {
-	return &verifierClient{
-		secret: secret,
-		url:    cloudflareTurnstileUrl,
+func NewVerifierClient(secret string, opts ...Option) Verifier {
+	return NewVerifierClientWithURL(secret, cloudflareTurnstileUrl, opts...)
+}
+
+func NewVerifierClientWithURL(secret string, url string, opts ...Option) Verifier {
+	return newVerifierClient(staticSecret(secret), url, opts)
+}
+
+// NewVerifierClientWithSecretFunc fetches the secret from secretFunc on every
+// call, allowing secrets to be rotated without downtime. secretFunc should
+// cache the secret if fetching it is expensive.
+func NewVerifierClientWithSecretFunc(secretFunc func(ctx context.Context) (string, error), opts ...Option) Verifier {
+	return newVerifierClient(secretFunc, cloudflareTurnstileUrl, opts)
+}
+
+func staticSecret(secret string) func(ctx context.Context) (string, error) {
+	return func(context.Context) (string, error) {
+		return secret, nil
 	}
 }
 
-func NewVerifierClientWithURL(secret string, url string) Verifier {
-	return &verifierClient{
-		secret: secret,
-		url:    url,
+func newVerifierClient(secretFunc func(ctx context.Context) (string, error), url string, opts []Option) *verifierClient {
+	t := &verifierClient{
+		secretFunc:       secretFunc,
+		url:              url,
+		acceptHeader:     defaultAcceptHeader,
+		maxAttempts:      1,
+		maxTokenLength:   DefaultMaxTokenLength,
+		jsonCodec:        stdJSONCodec{},
+		batchConcurrency: DefaultBatchConcurrency,
 	}
+
+	t.apply(opts)
+
+	return t
+}
+
+// WithOptions returns a copy of the client with opts applied on top of its
+// current options, leaving the original untouched. It is reachable through a
+// type assertion on the Verifier returned by the constructors:
+//
+//	v.(interface{ WithOptions(...Option) Verifier }).WithOptions(opts...)
+func (t *verifierClient) WithOptions(opts ...Option) Verifier {
+	clone := *t
+	clone.apply(opts)
+
+	return &clone
+}
+
+// URLProvider is implemented by the Verifier returned by the constructors,
+// reporting the siteverify endpoint it targets for diagnostics.
+type URLProvider interface {
+	URL() string
+}
+
+func (t *verifierClient) URL() string {
+	return t.url
+}
+
+func (t *verifierClient) apply(opts []Option) {
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	t.httpClient = t.customClient
+	if t.httpClient == nil {
+		t.httpClient = t.newHTTPClient()
+	}
+}
+
+func (t *verifierClient) newHTTPClient() *http.Client {
+	if t.tlsConfig == nil && !t.insecureSkipVerify && t.dialContext == nil {
+		return &http.Client{}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if t.tlsConfig != nil || t.insecureSkipVerify {
+		tlsConfig := &tls.Config{}
+		if t.tlsConfig != nil {
+			tlsConfig = t.tlsConfig.Clone()
+		}
+
+		if t.insecureSkipVerify {
+			tlsConfig.InsecureSkipVerify = true
+		}
+
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if t.dialContext != nil {
+		transport.DialContext = t.dialContext
+	}
+
+	return &http.Client{Transport: transport}
 }
 
 func (t *verifierClient) Verify(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
-	requestWithSecret := struct {
-		VerificationRequest `json:",inline"`
-		Secret              string `json:"secret"`
-	}{
-		VerificationRequest: *req,
-		Secret:              t.secret,
+	if t.eventSink == nil {
+		return t.collapse(t.verify(ctx, req))
+	}
+
+	start := time.Now()
+	resp, err := t.verify(ctx, req)
+	t.eventSink(ctx, newVerifyEvent(resp, err, time.Since(start)))
+
+	return t.collapse(resp, err)
+}
+
+func (t *verifierClient) collapse(resp *VerificationResponse, err error) (*VerificationResponse, error) {
+	if !t.collapseErrors || err == nil || errors.Is(err, ErrValidationFailed) {
+		return resp, err
 	}
 
-	requestJSON, err := json.Marshal(requestWithSecret)
+	return resp, &collapsedError{err: err}
+}
+
+func (t *verifierClient) verify(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+	if t.totalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.totalTimeout)
+		defer cancel()
+	}
+
+	if t.stats != nil {
+		t.stats.verifications.Add(1)
+	}
+
+	if t.requireRemoteIP && req.RemoteIP == "" {
+		return nil, fmt.Errorf("remote IP is required: %w", ErrInvalidRequest)
+	}
+
+	if t.maxTokenLength > 0 && len(req.Response) > t.maxTokenLength {
+		return nil, fmt.Errorf("response %s of %d bytes exceeds maximum token length %d: %w",
+			maskToken(req.Response), len(req.Response), t.maxTokenLength, ErrTokenTooLong)
+	}
+
+	if t.tokenTTL > 0 && !req.IssuedAt.IsZero() && time.Since(req.IssuedAt) > t.tokenTTL {
+		return nil, fmt.Errorf("token %s issued %s ago: %w",
+			maskToken(req.Response), time.Since(req.IssuedAt).Round(time.Second), ErrChallengeExpired)
+	}
+
+	secret, err := t.secretFunc(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can not get turnstile secret: %w", err)
+	}
+
+	payload := *req
+	if t.deriveIdempotencyKey && payload.IdempotencyKey == "" {
+		payload.IdempotencyKey = deriveIdempotencyKey(req.Response)
+	}
+
+	resp, err := t.postWithRetries(ctx, secret, &payload)
 	if err != nil {
-		return nil, fmt.Errorf("can not marshall verification request to JSON: %w", err)
+		return resp, err
+	}
+
+	return resp, t.checkExpectations(ctx, resp)
+}
+
+func (t *verifierClient) postWithRetries(ctx context.Context, secret string, req *VerificationRequest) (*VerificationResponse, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := t.postWithTimeout(ctx, secret, req)
+		if err == nil || attempt >= t.maxAttempts || !isRetryable(err) {
+			return resp, err
+		}
+
+		if sleepErr := sleep(ctx, t.retryDelay(attempt, err)); sleepErr != nil {
+			return resp, fmt.Errorf("retrying after %w: %w", err, sleepErr)
+		}
+	}
+}
+
+func (t *verifierClient) postWithTimeout(ctx context.Context, secret string, req *VerificationRequest) (*VerificationResponse, error) {
+	if t.perRequestTimeout <= 0 {
+		return t.post(ctx, secret, req)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewBuffer(requestJSON))
+	ctx, cancel := context.WithTimeout(ctx, t.perRequestTimeout)
+	defer cancel()
+
+	return t.post(ctx, secret, req)
+}
+
+func (t *verifierClient) post(ctx context.Context, secret string, req *VerificationRequest) (*VerificationResponse, error) {
+	buildRequest := t.requestBuilder
+	if buildRequest == nil {
+		buildRequest = t.buildJSONRequest
+	}
+
+	httpReq, err := buildRequest(ctx, t.url, secret, req)
 	if err != nil {
 		return nil, fmt.Errorf("can not create HTTP request: %w", err)
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
+	if t.acceptHeader != "" && httpReq.Header.Get("Accept") == "" {
+		httpReq.Header.Set("Accept", t.acceptHeader)
+	}
+
+	if t.tracePropagator != nil {
+		t.tracePropagator(ctx, httpReq.Header)
+	}
 
-	client := &http.Client{}
-	httpResp, err := client.Do(httpReq)
+	if t.stats != nil {
+		t.stats.attempts.Add(1)
+	}
+
+	httpResp, err := t.httpClient.Do(httpReq)
+	releaseRequestBody(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("error sending HTTP request: %w", err)
+		return nil, transportError(fmt.Errorf("error sending HTTP request: %w", err))
 	}
 	defer httpResp.Body.Close()
 
-	resp := &VerificationResponse{}
-	if err := json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
-		return nil, fmt.Errorf("can not decode turnstile response into JSON: %w", err)
+	if httpResp.StatusCode < 200 || httpResp.StatusCode > 299 {
+		return nil, transportError(&HTTPStatusError{
+			StatusCode: httpResp.StatusCode,
+			RetryAfter: parseRetryAfter(httpResp.Header.Get("Retry-After"), time.Now()),
+		})
 	}
 
-	if !resp.Success {
-		return resp, mapErrorCodes(resp.ErrorCodes)
-	}
+	// Transports other than http.Transport may not abort body reads once ctx
+	// is done, so close the body to unblock a stalled read.
+	stop := context.AfterFunc(ctx, func() { httpResp.Body.Close() })
+	defer stop()
 
-	return resp, nil
-}
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseBytes))
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
 
-func mapErrorCodes(codes []turnstileErrorCode) error {
-	switch {
-	case slices.Contains(codes, internalError):
-		return errors.New("turnstile server error")
+		return nil, transportError(fmt.Errorf("can not read turnstile response: %w", err))
+	}
 
-	case slices.Contains(codes, invalidInputResponse) || slices.Contains(codes, timeoutOrDuplicate):
-		return fmt.Errorf("invalid, duplicate or expired response: %v %w", codes, ErrValidationFailed)
+	if t.rawResponseSink != nil {
+		t.rawResponseSink(bytes.Clone(body))
+	}
 
-	case slices.Contains(codes, badRequest) || slices.Contains(codes, missingInputSecret) ||
-		slices.Contains(codes, invalidInputSecret) || slices.Contains(codes, invalidParsedSecret) ||
-		slices.Contains(codes, missingInputSecret) || slices.Contains(codes, invalidWidgetID) ||
-		slices.Contains(codes, missingInputResponse):
-		return fmt.Errorf("validation error(s) on turnstile: %v %w", codes, ErrInvalidRequest)
+	resp := &VerificationResponse{}
+	if err := t.jsonCodec.Decode(body, resp); err != nil {
+		// Hand back whatever could be salvaged to aid debugging; the error still
+		// marks the verification as failed.
+		return decodePartialResponse(body), transportError(fmt.Errorf("can not decode turnstile response into JSON: %w", err))
+	}
 
-	default:
-		return fmt.Errorf("unhandled turnstile error: %v", codes)
+	if !resp.Success {
+		return resp, mapErrorCodes(resp.ErrorCodes)
 	}
+
+	return resp, nil
 }
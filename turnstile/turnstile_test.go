@@ -0,0 +1,263 @@
+package turnstile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithAcceptHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{name: "default", want: "application/json"},
+		{name: "custom", opts: []Option{WithAcceptHeader("application/vnd.siteverify+json")}, want: "application/vnd.siteverify+json"},
+		{name: "omitted", opts: []Option{WithAcceptHeader("")}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("Accept")
+				respondJSON(`{"success":true}`)(w, r)
+			})
+
+			v := NewVerifierClientWithURL("secret", srv.URL, tt.opts...)
+			if _, err := v.Verify(context.Background(), &VerificationRequest{Response: "token"}); err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Accept = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerificationResponseChallengeTs(t *testing.T) {
+	tests := []struct {
+		name string
+		ts   string
+		want time.Time
+	}{
+		{name: "rfc3339", ts: `"2024-03-01T12:30:00.123Z"`, want: time.Date(2024, 3, 1, 12, 30, 0, 123e6, time.UTC)},
+		{name: "epoch seconds", ts: `1709296200`, want: time.Unix(1709296200, 0).UTC()},
+		{name: "epoch string", ts: `"1709296200"`, want: time.Unix(1709296200, 0).UTC()},
+		{name: "garbage", ts: `"yesterday"`},
+		{name: "object", ts: `{"seconds":1}`},
+		{name: "null", ts: `null`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp VerificationResponse
+			data := `{"success":true,"hostname":"example.com","challenge_ts":` + tt.ts + `}`
+			if err := json.Unmarshal([]byte(data), &resp); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+
+			if !resp.ChallengeTs.Equal(tt.want) {
+				t.Errorf("ChallengeTs = %v, want %v", resp.ChallengeTs, tt.want)
+			}
+
+			if !resp.Success || resp.Hostname != "example.com" {
+				t.Errorf("response = %+v, want the other fields decoded", resp)
+			}
+		})
+	}
+}
+
+func TestTokenVerifier(t *testing.T) {
+	innerErr := errors.New("rejected")
+
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{name: "success"},
+		{name: "failure", err: innerErr, wantErr: innerErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *VerificationRequest
+			v := NewTokenVerifier(verifierFunc(func(ctx context.Context, req *VerificationRequest) (*VerificationResponse, error) {
+				got = req
+				return &VerificationResponse{Success: tt.err == nil}, tt.err
+			}))
+
+			if err := v.VerifyToken(context.Background(), "token"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if got == nil || got.Response != "token" {
+				t.Errorf("Verify() request = %+v, want Response %q", got, "token")
+			}
+		})
+	}
+}
+
+func TestNewVerifierClientWithSecretFunc(t *testing.T) {
+	var received []string
+	srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding siteverify request: %v", err)
+		}
+
+		received = append(received, body["secret"])
+		respondJSON(`{"success":true}`)(w, r)
+	})
+	routeDefaultClients(t, srv.URL)
+
+	secrets := []string{"old-secret", "new-secret"}
+	var calls int
+	v := NewVerifierClientWithSecretFunc(func(ctx context.Context) (string, error) {
+		secret := secrets[min(calls, len(secrets)-1)]
+		calls++
+		return secret, nil
+	})
+
+	for range secrets {
+		if err := verifyToken(v); err != nil {
+			t.Fatalf("Verify() error = %v", err)
+		}
+	}
+
+	if len(received) != 2 || received[0] != "old-secret" || received[1] != "new-secret" {
+		t.Errorf("secrets sent = %q, want %q", received, secrets)
+	}
+}
+
+func TestNewVerifierClientWithSecretFuncError(t *testing.T) {
+	srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("siteverify called although the secret could not be fetched")
+	})
+	routeDefaultClients(t, srv.URL)
+
+	errSecret := errors.New("secrets manager unavailable")
+	v := NewVerifierClientWithSecretFunc(func(ctx context.Context) (string, error) {
+		return "", errSecret
+	})
+
+	if err := verifyToken(v); !errors.Is(err, errSecret) {
+		t.Errorf("Verify() error = %v, want %v", err, errSecret)
+	}
+}
+
+// newStallingServer starts a siteverify stub sending the headers and the start
+// of the body, then stalling until the test ends.
+func newStallingServer(t *testing.T) *httptest.Server {
+	release := make(chan struct{})
+	srv := newSiteverifyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"success":`))
+		w.(http.Flusher).Flush()
+
+		<-release
+	})
+	t.Cleanup(func() { close(release) })
+
+	return srv
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestVerifyStalledBodyHonorsDeadline(t *testing.T) {
+	// stalledTransport answers with a body that blocks until closed, like
+	// transports not aborting reads when the request context is done.
+	stalledTransport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.Pipe()
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       body,
+			Request:    req,
+		}, nil
+	})
+
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		opts    func(url string) Verifier
+	}{
+		{name: "context deadline", timeout: 50 * time.Millisecond, opts: func(url string) Verifier {
+			return NewVerifierClientWithURL("secret", url)
+		}},
+		{name: "per-request timeout", opts: func(url string) Verifier {
+			return NewVerifierClientWithURL("secret", url, WithPerRequestTimeout(50*time.Millisecond))
+		}},
+		{name: "custom transport", timeout: 50 * time.Millisecond, opts: func(url string) Verifier {
+			return NewVerifierClientWithURL("secret", url, WithHTTPClient(&http.Client{Transport: stalledTransport}))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.opts(newStallingServer(t).URL)
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			start := time.Now()
+			_, err := v.Verify(ctx, &VerificationRequest{Response: "token"})
+
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Verify() took %v, want it to stop at the deadline", elapsed)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Verify() error = %v, want it to wrap context.DeadlineExceeded", err)
+			}
+			if got := KindOf(err); got != OutcomeKindTransportError {
+				t.Errorf("KindOf(%v) = %v, want %v", err, got, OutcomeKindTransportError)
+			}
+		})
+	}
+}
+
+func TestURLProvider(t *testing.T) {
+	const custom = "https://siteverify.example.com/siteverify"
+
+	tests := []struct {
+		name     string
+		verifier Verifier
+		want     string
+	}{
+		{name: "default", verifier: NewVerifierClient("secret"), want: cloudflareTurnstileUrl},
+		{name: "custom", verifier: NewVerifierClientWithURL("secret", custom), want: custom},
+		{name: "secret func", verifier: NewVerifierClientWithSecretFunc(func(context.Context) (string, error) {
+			return "secret", nil
+		}), want: cloudflareTurnstileUrl},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, ok := tt.verifier.(URLProvider)
+			if !ok {
+				t.Fatalf("%T does not implement URLProvider", tt.verifier)
+			}
+
+			if got := provider.URL(); got != tt.want {
+				t.Errorf("URL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}